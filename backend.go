@@ -0,0 +1,312 @@
+package space
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// Backend is the storage behind a Space: an S3 compatible service, memory or local directory.
+type Backend interface {
+	ListBuckets(ctx context.Context) ([]BucketInfo, error)
+	ListObjects(ctx context.Context, bucketName, objectPrefix string, recursive bool) ([]ObjectInfo, error)
+	// ListObjectsPage lists at most `options.PageSize` objects sorted by key, continuing from `token` of previous page.
+	// Next token is empty on the last page.
+	ListObjectsPage(ctx context.Context, bucketName string, options ListOptions, token string) (objects []ObjectInfo, next string, err error)
+	PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, options PutObjectOptions) (int64, error)
+	GetObject(ctx context.Context, bucketName, objectName string, options GetObjectOptions) (Object, error)
+	StatObject(ctx context.Context, bucketName, objectName string, options StatObjectOptions) (ObjectInfo, error)
+	RemoveObject(ctx context.Context, bucketName, objectName string) error
+	RemoveObjects(ctx context.Context, bucketName string, objectNames []string) []RemoveObjectError
+	PutObjectTagging(ctx context.Context, bucketName, objectName string, tags map[string]string) error
+	GetObjectTagging(ctx context.Context, bucketName, objectName string) (map[string]string, error)
+	RemoveObjectTagging(ctx context.Context, bucketName, objectName string) error
+	// CopyObject server side, its metadata and tags are replaced with the ones in `options`.
+	CopyObject(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string, options PutObjectOptions) error
+
+	NewMultipartUpload(ctx context.Context, bucketName, objectName string, options PutObjectOptions) (uploadID string, err error)
+	PutObjectPart(ctx context.Context, bucketName, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (ObjectPart, error)
+	CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart) error
+	AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error
+	ListMultipartUploads(ctx context.Context, bucketName, objectPrefix string) ([]ObjectMultipartInfo, error)
+	ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string) ([]ObjectPart, error)
+	// CopyObjectPart copies `length` bytes of an object starting at `offset` server side, as a part of a multipart upload.
+	CopyObjectPart(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject, uploadID string, partNumber int, offset, length int64) (CompletePart, error)
+
+	// PresignGetObject returns a URL to download an object without credentials until it expires.
+	PresignGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error)
+	// PresignPutObject returns a URL to upload an object without credentials until it expires.
+	PresignPutObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error)
+}
+
+// Object represents an open object.
+type Object interface {
+	io.ReadCloser
+	io.ReaderAt
+	io.Seeker
+
+	// Stat of the object.
+	Stat() (ObjectInfo, error)
+}
+
+// ObjectPart of an incomplete multipart upload.
+type ObjectPart = minio.ObjectPart
+
+// ObjectMultipartInfo describes an incomplete multipart upload.
+type ObjectMultipartInfo = minio.ObjectMultipartInfo
+
+// CompletePart identifies an uploaded part when completing multipart upload.
+type CompletePart = minio.CompletePart
+
+// RemoveObjectError contains the name of an object that failed to be removed.
+type RemoveObjectError = minio.RemoveObjectError
+
+// errNoSuchBucket mimics S3 error response so callers handle every backend the same way.
+func errNoSuchBucket(bucketName string) error {
+	return minio.ErrorResponse{
+		StatusCode: http.StatusNotFound,
+		Code:       "NoSuchBucket",
+		Message:    "The specified bucket does not exist.",
+		BucketName: bucketName,
+	}
+}
+
+// errNoSuchKey mimics S3 error response so callers handle every backend the same way.
+func errNoSuchKey(bucketName, objectName string) error {
+	return minio.ErrorResponse{
+		StatusCode: http.StatusNotFound,
+		Code:       "NoSuchKey",
+		Message:    "The specified key does not exist.",
+		BucketName: bucketName,
+		Key:        objectName,
+	}
+}
+
+// errNoSuchUpload mimics S3 error response so callers handle every backend the same way.
+func errNoSuchUpload(bucketName, objectName, uploadID string) error {
+	return minio.ErrorResponse{
+		StatusCode: http.StatusNotFound,
+		Code:       "NoSuchUpload",
+		Message:    fmt.Sprintf("The specified multipart upload %v does not exist.", uploadID),
+		BucketName: bucketName,
+		Key:        objectName,
+	}
+}
+
+// errInvalidPart mimics S3 error response so callers handle every backend the same way.
+func errInvalidPart(bucketName, objectName string, partNumber int) error {
+	return minio.ErrorResponse{
+		StatusCode: http.StatusBadRequest,
+		Code:       "InvalidPart",
+		Message:    fmt.Sprintf("Part %v could not be found or its ETag doesn't match.", partNumber),
+		BucketName: bucketName,
+		Key:        objectName,
+	}
+}
+
+// errNotImplemented mimics S3 error response for features only S3 compatible services have, e.g. presigned URLs.
+func errNotImplemented(feature string) error {
+	return minio.ErrorResponse{
+		StatusCode: http.StatusNotImplemented,
+		Code:       "NotImplemented",
+		Message:    fmt.Sprintf("%v is only supported by S3 compatible services.", feature),
+	}
+}
+
+// multipartETag of a completed upload, md5 of concatenated part md5 followed by part count, like S3.
+func multipartETag(parts []CompletePart) (string, error) {
+	h := md5.New()
+	for _, part := range parts {
+		sum, err := hex.DecodeString(strings.Trim(part.ETag, `"`))
+		if err != nil {
+			return "", err
+		}
+		h.Write(sum)
+	}
+	return fmt.Sprintf("%v-%v", hex.EncodeToString(h.Sum(nil)), len(parts)), nil
+}
+
+// copyObjectPart by reading the source object, for non-S3 backends.
+func copyObjectPart(ctx context.Context, b Backend, srcBucket, srcObject, dstBucket, dstObject, uploadID string, partNumber int, offset, length int64) (CompletePart, error) {
+	object, err := b.GetObject(ctx, srcBucket, srcObject, GetObjectOptions{})
+	if err != nil {
+		return CompletePart{}, err
+	}
+	defer object.Close()
+
+	part, err := b.PutObjectPart(ctx, dstBucket, dstObject, uploadID, partNumber, io.NewSectionReader(object, offset, length), length)
+	if err != nil {
+		return CompletePart{}, err
+	}
+	return CompletePart{PartNumber: part.PartNumber, ETag: part.ETag}, nil
+}
+
+// newUploadID for non-S3 backends.
+func newUploadID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// filterObjects by prefix, sorted by key. Unless recursive, keys below the next "/"
+// are collapsed into a single prefix entry, like S3 delimiter listing.
+func filterObjects(objects []ObjectInfo, objectPrefix string, recursive bool) []ObjectInfo {
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	filtered := []ObjectInfo{}
+	seen := map[string]bool{}
+	for _, object := range objects {
+		if !strings.HasPrefix(object.Key, objectPrefix) {
+			continue
+		}
+		if !recursive {
+			rest := strings.TrimPrefix(object.Key, objectPrefix)
+			if i := strings.Index(rest, "/"); i >= 0 {
+				dir := objectPrefix + rest[:i+1]
+				if !seen[dir] {
+					seen[dir] = true
+					filtered = append(filtered, ObjectInfo{Key: dir})
+				}
+				continue
+			}
+		}
+		filtered = append(filtered, object)
+	}
+	return filtered
+}
+
+// pageObjects from a sorted listing, starting after `options.StartAfter` or `token` whichever is later.
+func pageObjects(objects []ObjectInfo, options ListOptions, token string) ([]ObjectInfo, string) {
+	after := options.StartAfter
+	if token > after {
+		after = token
+	}
+	start := sort.Search(len(objects), func(i int) bool {
+		return objects[i].Key > after
+	})
+	end := start + options.pageSize()
+	if end >= len(objects) {
+		return objects[start:], ""
+	}
+	return objects[start:end], objects[end-1].Key
+}
+
+type tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type tagging struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ Tagging"`
+	TagSet  struct {
+		Tags []tag `xml:"Tag"`
+	} `xml:"TagSet"`
+}
+
+// marshalTags into S3 tagging XML document, sorted by key.
+func marshalTags(tags map[string]string) (string, error) {
+	doc := tagging{}
+	for key, val := range tags {
+		doc.TagSet.Tags = append(doc.TagSet.Tags, tag{Key: key, Value: val})
+	}
+	sort.Slice(doc.TagSet.Tags, func(i, j int) bool {
+		return doc.TagSet.Tags[i].Key < doc.TagSet.Tags[j].Key
+	})
+
+	data, err := xml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data), nil
+}
+
+// unmarshalTags from S3 tagging XML document.
+func unmarshalTags(text string) (map[string]string, error) {
+	doc := tagging{}
+	if err := xml.Unmarshal([]byte(text), &doc); err != nil {
+		return nil, fmt.Errorf("Invalid tagging document: %v", err)
+	}
+
+	tags := make(map[string]string, len(doc.TagSet.Tags))
+	for _, t := range doc.TagSet.Tags {
+		tags[t.Key] = t.Value
+	}
+	return tags, nil
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	copied := make(map[string]string, len(tags))
+	for key, val := range tags {
+		copied[key] = val
+	}
+	return copied
+}
+
+// readerObject is an open object read from memory.
+type readerObject struct {
+	*bytes.Reader
+	info ObjectInfo
+}
+
+func (o readerObject) Close() error {
+	return nil
+}
+
+func (o readerObject) Stat() (ObjectInfo, error) {
+	return o.info, nil
+}
+
+// newObjectInfo for objects stored by non-S3 backends.
+func newObjectInfo(objectName string, size int64, etag string, modTime time.Time, options PutObjectOptions) ObjectInfo {
+	contentType := options.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := http.Header{}
+	userMetadata := minio.StringMap{}
+	for key, val := range options.UserMetadata {
+		header.Set("X-Amz-Meta-"+key, val)
+		userMetadata[http.CanonicalHeaderKey(key)] = val
+	}
+
+	return ObjectInfo{
+		Key:          objectName,
+		Size:         size,
+		ETag:         etag,
+		LastModified: modTime.UTC(),
+		ContentType:  contentType,
+		Metadata:     header,
+		UserMetadata: userMetadata,
+	}
+}
+
+// readObject from reader with md5 hex digest, reading exactly `objectSize` bytes unless it's negative.
+func readObject(reader io.Reader, objectSize int64) (data []byte, etag string, err error) {
+	if objectSize >= 0 {
+		data = make([]byte, objectSize)
+		_, err = io.ReadFull(reader, data)
+	} else {
+		data, err = ioutil.ReadAll(reader)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	sum := md5.Sum(data)
+	return data, hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,568 @@
+package space
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// localMetaDir holds object metadata and temporary files, hidden from bucket listing.
+const localMetaDir = ".space"
+
+// localMeta is stored next to each object since the file system can't hold it.
+type localMeta struct {
+	ETag         string            `json:"etag"`
+	ContentType  string            `json:"content_type,omitempty"`
+	UserMetadata map[string]string `json:"user_metadata,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+}
+
+// localBackend maps buckets to directories under root and objects to files, useful offline.
+// Unlike S3, a key can't be both an object and a prefix of another object, e.g. "a" and "a/b".
+type localBackend struct {
+	root string
+}
+
+// fileObject is an open object read from local file.
+type fileObject struct {
+	*os.File
+	info ObjectInfo
+}
+
+func (o fileObject) Stat() (ObjectInfo, error) {
+	return o.info, nil
+}
+
+// NewLocalBackend rooted at given directory, creating given buckets as sub-directories.
+func NewLocalBackend(root string, bucketNames ...string) (Backend, error) {
+	b := localBackend{root: root}
+	for _, name := range bucketNames {
+		if err := os.MkdirAll(b.bucketPath(name), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (b localBackend) bucketPath(bucketName string) string {
+	return filepath.Join(b.root, bucketName)
+}
+
+// objectPath in bucket directory, rejecting names that escape the bucket.
+func (b localBackend) objectPath(bucketName, objectName string) (string, error) {
+	fi, err := os.Stat(b.bucketPath(bucketName))
+	if err != nil || !fi.IsDir() || strings.HasPrefix(bucketName, ".") {
+		return "", errNoSuchBucket(bucketName)
+	}
+
+	clean := path.Clean("/" + objectName)
+	if objectName == "" || strings.HasSuffix(objectName, "/") || clean[1:] != objectName {
+		return "", minio.ErrInvalidArgument(fmt.Sprintf("Invalid object name %q", objectName))
+	}
+	return filepath.Join(b.bucketPath(bucketName), filepath.FromSlash(objectName)), nil
+}
+
+func (b localBackend) metaPath(bucketName, objectName string) string {
+	return filepath.Join(b.root, localMetaDir, "meta", bucketName, filepath.FromSlash(objectName)+".json")
+}
+
+func (b localBackend) readMeta(bucketName, objectName string) (meta localMeta, err error) {
+	data, err := ioutil.ReadFile(b.metaPath(bucketName, objectName))
+	if os.IsNotExist(err) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, err
+	}
+	err = json.Unmarshal(data, &meta)
+	return meta, err
+}
+
+func (b localBackend) writeMeta(bucketName, objectName string, meta localMeta) error {
+	fp := b.metaPath(bucketName, objectName)
+	if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fp, data, 0644)
+}
+
+// info of an object file. Files put outside of Space have their ETag computed on the fly.
+func (b localBackend) info(bucketName, objectName, fp string, fi os.FileInfo) (ObjectInfo, error) {
+	meta, err := b.readMeta(bucketName, objectName)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if meta.ETag == "" {
+		if meta.ETag, err = md5File(fp); err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+	return newObjectInfo(objectName, fi.Size(), meta.ETag, fi.ModTime(), PutObjectOptions{
+		ContentType:  meta.ContentType,
+		UserMetadata: meta.UserMetadata,
+	}), nil
+}
+
+func md5File(fp string) (string, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (b localBackend) ListBuckets(ctx context.Context) (buckets []BucketInfo, err error) {
+	fis, err := ioutil.ReadDir(b.root)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range fis {
+		if !fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		buckets = append(buckets, BucketInfo{Name: fi.Name(), CreationDate: fi.ModTime().UTC()})
+	}
+	return buckets, nil
+}
+
+func (b localBackend) ListObjects(ctx context.Context, bucketName, objectPrefix string, recursive bool) ([]ObjectInfo, error) {
+	root := b.bucketPath(bucketName)
+	if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+		return nil, errNoSuchBucket(bucketName)
+	}
+
+	objects := []ObjectInfo{}
+	err := filepath.Walk(root, func(fp string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, fp)
+		if err != nil {
+			return err
+		}
+		objectName := filepath.ToSlash(rel)
+		if !strings.HasPrefix(objectName, objectPrefix) {
+			return nil
+		}
+		info, err := b.info(bucketName, objectName, fp, fi)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return filterObjects(objects, objectPrefix, recursive), nil
+}
+
+func (b localBackend) ListObjectsPage(ctx context.Context, bucketName string, options ListOptions, token string) ([]ObjectInfo, string, error) {
+	objects, err := b.ListObjects(ctx, bucketName, options.Prefix, options.Recursive)
+	if err != nil {
+		return nil, "", err
+	}
+	objects, next := pageObjects(objects, options, token)
+	return objects, next, nil
+}
+
+func (b localBackend) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, options PutObjectOptions) (int64, error) {
+	fp, err := b.objectPath(bucketName, objectName)
+	if err != nil {
+		return 0, err
+	}
+
+	tmpDir := filepath.Join(b.root, localMetaDir, "tmp")
+	if err = os.MkdirAll(tmpDir, 0755); err != nil {
+		return 0, err
+	}
+	tmp, err := ioutil.TempFile(tmpDir, "put-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if objectSize >= 0 {
+		reader = io.LimitReader(reader, objectSize)
+	}
+	h := md5.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), reader)
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil && objectSize >= 0 && n != objectSize {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+		return 0, err
+	}
+	if err = os.Rename(tmp.Name(), fp); err != nil {
+		return 0, err
+	}
+
+	err = b.writeMeta(bucketName, objectName, localMeta{
+		ETag:         hex.EncodeToString(h.Sum(nil)),
+		ContentType:  options.ContentType,
+		UserMetadata: options.UserMetadata,
+		Tags:         options.UserTags,
+	})
+	return n, err
+}
+
+func (b localBackend) GetObject(ctx context.Context, bucketName, objectName string, options GetObjectOptions) (Object, error) {
+	info, err := b.StatObject(ctx, bucketName, objectName, StatObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	fp, _ := b.objectPath(bucketName, objectName)
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	return fileObject{File: f, info: info}, nil
+}
+
+func (b localBackend) StatObject(ctx context.Context, bucketName, objectName string, options StatObjectOptions) (ObjectInfo, error) {
+	fp, err := b.objectPath(bucketName, objectName)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	fi, err := os.Stat(fp)
+	if err != nil || fi.IsDir() {
+		return ObjectInfo{}, errNoSuchKey(bucketName, objectName)
+	}
+	return b.info(bucketName, objectName, fp, fi)
+}
+
+func (b localBackend) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	fp, err := b.objectPath(bucketName, objectName)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(fp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err = os.Remove(b.metaPath(bucketName, objectName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Like S3, directories exist only as long as they hold objects.
+	bucketPath := b.bucketPath(bucketName)
+	for dir := filepath.Dir(fp); dir != bucketPath; dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (b localBackend) RemoveObjects(ctx context.Context, bucketName string, objectNames []string) (errs []RemoveObjectError) {
+	for _, name := range objectNames {
+		if err := b.RemoveObject(ctx, bucketName, name); err != nil {
+			errs = append(errs, RemoveObjectError{ObjectName: name, Err: err})
+		}
+	}
+	return errs
+}
+
+func (b localBackend) PutObjectTagging(ctx context.Context, bucketName, objectName string, tags map[string]string) error {
+	if _, err := b.StatObject(ctx, bucketName, objectName, StatObjectOptions{}); err != nil {
+		return err
+	}
+	meta, err := b.readMeta(bucketName, objectName)
+	if err != nil {
+		return err
+	}
+	meta.Tags = copyTags(tags)
+	return b.writeMeta(bucketName, objectName, meta)
+}
+
+func (b localBackend) GetObjectTagging(ctx context.Context, bucketName, objectName string) (map[string]string, error) {
+	if _, err := b.StatObject(ctx, bucketName, objectName, StatObjectOptions{}); err != nil {
+		return nil, err
+	}
+	meta, err := b.readMeta(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return copyTags(meta.Tags), nil
+}
+
+func (b localBackend) RemoveObjectTagging(ctx context.Context, bucketName, objectName string) error {
+	return b.PutObjectTagging(ctx, bucketName, objectName, nil)
+}
+
+func (b localBackend) CopyObject(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string, options PutObjectOptions) error {
+	object, err := b.GetObject(ctx, srcBucket, srcObject, GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	info, err := object.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = b.PutObject(ctx, dstBucket, dstObject, object, info.Size, options)
+	return err
+}
+
+func (b localBackend) CopyObjectPart(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject, uploadID string, partNumber int, offset, length int64) (CompletePart, error) {
+	return copyObjectPart(ctx, b, srcBucket, srcObject, dstBucket, dstObject, uploadID, partNumber, offset, length)
+}
+
+// localUpload describes an incomplete multipart upload, stored with its parts.
+type localUpload struct {
+	BucketName   string            `json:"bucket"`
+	ObjectName   string            `json:"object"`
+	Initiated    time.Time         `json:"initiated"`
+	ContentType  string            `json:"content_type,omitempty"`
+	UserMetadata map[string]string `json:"user_metadata,omitempty"`
+	UserTags     map[string]string `json:"user_tags,omitempty"`
+}
+
+func (b localBackend) uploadPath(uploadID string) string {
+	return filepath.Join(b.root, localMetaDir, "uploads", uploadID)
+}
+
+func (b localBackend) partPath(uploadID string, partNumber int) string {
+	return filepath.Join(b.uploadPath(uploadID), strconv.Itoa(partNumber))
+}
+
+func (b localBackend) upload(bucketName, objectName, uploadID string) (upload localUpload, err error) {
+	if uploadID == "" || filepath.Base(uploadID) != uploadID || strings.HasPrefix(uploadID, ".") {
+		return upload, errNoSuchUpload(bucketName, objectName, uploadID)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(b.uploadPath(uploadID), "upload.json"))
+	if err == nil {
+		err = json.Unmarshal(data, &upload)
+	}
+	if err != nil || upload.BucketName != bucketName || upload.ObjectName != objectName {
+		return upload, errNoSuchUpload(bucketName, objectName, uploadID)
+	}
+	return upload, nil
+}
+
+func (b localBackend) NewMultipartUpload(ctx context.Context, bucketName, objectName string, options PutObjectOptions) (uploadID string, err error) {
+	if _, err = b.objectPath(bucketName, objectName); err != nil {
+		return "", err
+	}
+
+	uploadID = newUploadID()
+	if err = os.MkdirAll(b.uploadPath(uploadID), 0755); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(localUpload{
+		BucketName:   bucketName,
+		ObjectName:   objectName,
+		Initiated:    time.Now().UTC(),
+		ContentType:  options.ContentType,
+		UserMetadata: options.UserMetadata,
+		UserTags:     options.UserTags,
+	})
+	if err != nil {
+		return "", err
+	}
+	return uploadID, ioutil.WriteFile(filepath.Join(b.uploadPath(uploadID), "upload.json"), data, 0644)
+}
+
+func (b localBackend) PutObjectPart(ctx context.Context, bucketName, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (ObjectPart, error) {
+	if _, err := b.upload(bucketName, objectName, uploadID); err != nil {
+		return ObjectPart{}, err
+	}
+
+	tmp, err := ioutil.TempFile(b.uploadPath(uploadID), "part-")
+	if err != nil {
+		return ObjectPart{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := md5.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(reader, partSize))
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil && n != partSize {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), b.partPath(uploadID, partNumber))
+	}
+	if err != nil {
+		return ObjectPart{}, err
+	}
+
+	return ObjectPart{
+		PartNumber:   partNumber,
+		ETag:         hex.EncodeToString(h.Sum(nil)),
+		Size:         n,
+		LastModified: time.Now().UTC(),
+	}, nil
+}
+
+func (b localBackend) CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart) error {
+	upload, err := b.upload(bucketName, objectName, uploadID)
+	if err != nil {
+		return err
+	}
+	for _, part := range parts {
+		etag, err := md5File(b.partPath(uploadID, part.PartNumber))
+		if err != nil || etag != part.ETag {
+			return errInvalidPart(bucketName, objectName, part.PartNumber)
+		}
+	}
+	etag, err := multipartETag(parts)
+	if err != nil {
+		return err
+	}
+
+	readers := make([]io.Reader, len(parts))
+	for i, part := range parts {
+		f, err := os.Open(b.partPath(uploadID, part.PartNumber))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers[i] = f
+	}
+
+	options := PutObjectOptions{
+		ContentType:  upload.ContentType,
+		UserMetadata: upload.UserMetadata,
+		UserTags:     upload.UserTags,
+	}
+	if _, err = b.PutObject(ctx, bucketName, objectName, io.MultiReader(readers...), -1, options); err != nil {
+		return err
+	}
+
+	meta, err := b.readMeta(bucketName, objectName)
+	if err != nil {
+		return err
+	}
+	meta.ETag = etag
+	if err = b.writeMeta(bucketName, objectName, meta); err != nil {
+		return err
+	}
+	return os.RemoveAll(b.uploadPath(uploadID))
+}
+
+func (b localBackend) AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error {
+	if _, err := b.upload(bucketName, objectName, uploadID); err != nil {
+		return err
+	}
+	return os.RemoveAll(b.uploadPath(uploadID))
+}
+
+func (b localBackend) ListMultipartUploads(ctx context.Context, bucketName, objectPrefix string) (uploads []ObjectMultipartInfo, err error) {
+	if fi, err := os.Stat(b.bucketPath(bucketName)); err != nil || !fi.IsDir() {
+		return nil, errNoSuchBucket(bucketName)
+	}
+
+	fis, err := ioutil.ReadDir(filepath.Join(b.root, localMetaDir, "uploads"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range fis {
+		data, err := ioutil.ReadFile(filepath.Join(b.uploadPath(fi.Name()), "upload.json"))
+		if err != nil {
+			continue
+		}
+		upload := localUpload{}
+		if err = json.Unmarshal(data, &upload); err != nil {
+			continue
+		}
+		if upload.BucketName != bucketName || !strings.HasPrefix(upload.ObjectName, objectPrefix) {
+			continue
+		}
+
+		info := ObjectMultipartInfo{Key: upload.ObjectName, UploadID: fi.Name(), Initiated: upload.Initiated}
+		parts, err := b.ListObjectParts(ctx, bucketName, upload.ObjectName, fi.Name())
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			info.Size += part.Size
+		}
+		uploads = append(uploads, info)
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].Key < uploads[j].Key
+	})
+	return uploads, nil
+}
+
+func (b localBackend) ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string) (parts []ObjectPart, err error) {
+	if _, err = b.upload(bucketName, objectName, uploadID); err != nil {
+		return nil, err
+	}
+	fis, err := ioutil.ReadDir(b.uploadPath(uploadID))
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range fis {
+		partNumber, err := strconv.Atoi(fi.Name())
+		if err != nil {
+			continue
+		}
+		etag, err := md5File(b.partPath(uploadID, partNumber))
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, ObjectPart{
+			PartNumber:   partNumber,
+			ETag:         etag,
+			Size:         fi.Size(),
+			LastModified: fi.ModTime().UTC(),
+		})
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	return parts, nil
+}
+
+func (b localBackend) PresignGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error) {
+	return nil, errNotImplemented("Presigned URL")
+}
+
+func (b localBackend) PresignPutObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error) {
+	return nil, errNotImplemented("Presigned URL")
+}
@@ -0,0 +1,373 @@
+package space
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data []byte
+	info ObjectInfo
+	tags map[string]string
+}
+
+type memoryBucket struct {
+	info    BucketInfo
+	objects map[string]memoryObject
+}
+
+type memoryUpload struct {
+	bucketName string
+	objectName string
+	options    PutObjectOptions
+	initiated  time.Time
+	parts      map[int]memoryObject
+}
+
+// memoryBackend keeps every bucket in memory, useful for unit tests.
+type memoryBackend struct {
+	mu      *sync.RWMutex
+	buckets map[string]*memoryBucket
+	uploads map[string]*memoryUpload
+}
+
+// NewMemoryBackend with given empty buckets.
+func NewMemoryBackend(bucketNames ...string) Backend {
+	b := memoryBackend{
+		mu:      &sync.RWMutex{},
+		buckets: map[string]*memoryBucket{},
+		uploads: map[string]*memoryUpload{},
+	}
+	for _, name := range bucketNames {
+		b.buckets[name] = &memoryBucket{
+			info:    BucketInfo{Name: name, CreationDate: time.Now().UTC()},
+			objects: map[string]memoryObject{},
+		}
+	}
+	return b
+}
+
+func (b memoryBackend) bucket(bucketName string) (*memoryBucket, error) {
+	bucket, ok := b.buckets[bucketName]
+	if !ok {
+		return nil, errNoSuchBucket(bucketName)
+	}
+	return bucket, nil
+}
+
+func (b memoryBackend) object(bucketName, objectName string) (memoryObject, error) {
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return memoryObject{}, err
+	}
+	object, ok := bucket.objects[objectName]
+	if !ok {
+		return object, errNoSuchKey(bucketName, objectName)
+	}
+	return object, nil
+}
+
+func (b memoryBackend) ListBuckets(ctx context.Context) (buckets []BucketInfo, err error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, bucket := range b.buckets {
+		buckets = append(buckets, bucket.info)
+	}
+	return buckets, nil
+}
+
+func (b memoryBackend) ListObjects(ctx context.Context, bucketName, objectPrefix string, recursive bool) ([]ObjectInfo, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]ObjectInfo, 0, len(bucket.objects))
+	for _, object := range bucket.objects {
+		objects = append(objects, object.info)
+	}
+	return filterObjects(objects, objectPrefix, recursive), nil
+}
+
+func (b memoryBackend) ListObjectsPage(ctx context.Context, bucketName string, options ListOptions, token string) ([]ObjectInfo, string, error) {
+	objects, err := b.ListObjects(ctx, bucketName, options.Prefix, options.Recursive)
+	if err != nil {
+		return nil, "", err
+	}
+	objects, next := pageObjects(objects, options, token)
+	return objects, next, nil
+}
+
+func (b memoryBackend) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, options PutObjectOptions) (int64, error) {
+	data, etag, err := readObject(reader, objectSize)
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return 0, err
+	}
+	bucket.objects[objectName] = memoryObject{
+		data: data,
+		info: newObjectInfo(objectName, int64(len(data)), etag, time.Now(), options),
+		tags: copyTags(options.UserTags),
+	}
+	return int64(len(data)), nil
+}
+
+func (b memoryBackend) GetObject(ctx context.Context, bucketName, objectName string, options GetObjectOptions) (Object, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	object, err := b.object(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return readerObject{Reader: bytes.NewReader(object.data), info: object.info}, nil
+}
+
+func (b memoryBackend) StatObject(ctx context.Context, bucketName, objectName string, options StatObjectOptions) (ObjectInfo, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	object, err := b.object(bucketName, objectName)
+	return object.info, err
+}
+
+func (b memoryBackend) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	delete(bucket.objects, objectName)
+	return nil
+}
+
+func (b memoryBackend) RemoveObjects(ctx context.Context, bucketName string, objectNames []string) (errs []RemoveObjectError) {
+	for _, name := range objectNames {
+		if err := b.RemoveObject(ctx, bucketName, name); err != nil {
+			errs = append(errs, RemoveObjectError{ObjectName: name, Err: err})
+		}
+	}
+	return errs
+}
+
+func (b memoryBackend) PutObjectTagging(ctx context.Context, bucketName, objectName string, tags map[string]string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	object, err := b.object(bucketName, objectName)
+	if err != nil {
+		return err
+	}
+	object.tags = copyTags(tags)
+	b.buckets[bucketName].objects[objectName] = object
+	return nil
+}
+
+func (b memoryBackend) GetObjectTagging(ctx context.Context, bucketName, objectName string) (map[string]string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	object, err := b.object(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return copyTags(object.tags), nil
+}
+
+func (b memoryBackend) RemoveObjectTagging(ctx context.Context, bucketName, objectName string) error {
+	return b.PutObjectTagging(ctx, bucketName, objectName, nil)
+}
+
+func (b memoryBackend) CopyObject(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string, options PutObjectOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	object, err := b.object(srcBucket, srcObject)
+	if err != nil {
+		return err
+	}
+	bucket, err := b.bucket(dstBucket)
+	if err != nil {
+		return err
+	}
+	// Like S3, a copy has a single part ETag even if the source was uploaded in parts.
+	sum := md5.Sum(object.data)
+	bucket.objects[dstObject] = memoryObject{
+		data: object.data,
+		info: newObjectInfo(dstObject, int64(len(object.data)), hex.EncodeToString(sum[:]), time.Now(), options),
+		tags: copyTags(options.UserTags),
+	}
+	return nil
+}
+
+func (b memoryBackend) CopyObjectPart(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject, uploadID string, partNumber int, offset, length int64) (CompletePart, error) {
+	return copyObjectPart(ctx, b, srcBucket, srcObject, dstBucket, dstObject, uploadID, partNumber, offset, length)
+}
+
+func (b memoryBackend) upload(bucketName, objectName, uploadID string) (*memoryUpload, error) {
+	upload, ok := b.uploads[uploadID]
+	if !ok || upload.bucketName != bucketName || upload.objectName != objectName {
+		return nil, errNoSuchUpload(bucketName, objectName, uploadID)
+	}
+	return upload, nil
+}
+
+func (b memoryBackend) NewMultipartUpload(ctx context.Context, bucketName, objectName string, options PutObjectOptions) (uploadID string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err = b.bucket(bucketName); err != nil {
+		return "", err
+	}
+	uploadID = newUploadID()
+	b.uploads[uploadID] = &memoryUpload{
+		bucketName: bucketName,
+		objectName: objectName,
+		options:    options,
+		initiated:  time.Now().UTC(),
+		parts:      map[int]memoryObject{},
+	}
+	return uploadID, nil
+}
+
+func (b memoryBackend) PutObjectPart(ctx context.Context, bucketName, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (ObjectPart, error) {
+	data, etag, err := readObject(reader, partSize)
+	if err != nil {
+		return ObjectPart{}, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	upload, err := b.upload(bucketName, objectName, uploadID)
+	if err != nil {
+		return ObjectPart{}, err
+	}
+	part := ObjectPart{
+		PartNumber:   partNumber,
+		ETag:         etag,
+		Size:         int64(len(data)),
+		LastModified: time.Now().UTC(),
+	}
+	upload.parts[partNumber] = memoryObject{data: data, info: ObjectInfo{ETag: etag, Size: part.Size, LastModified: part.LastModified}}
+	return part, nil
+}
+
+func (b memoryBackend) CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	upload, err := b.upload(bucketName, objectName, uploadID)
+	if err != nil {
+		return err
+	}
+
+	data := []byte{}
+	for _, part := range parts {
+		uploaded, ok := upload.parts[part.PartNumber]
+		if !ok || uploaded.info.ETag != part.ETag {
+			return errInvalidPart(bucketName, objectName, part.PartNumber)
+		}
+		data = append(data, uploaded.data...)
+	}
+	etag, err := multipartETag(parts)
+	if err != nil {
+		return err
+	}
+
+	bucket, err := b.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	bucket.objects[objectName] = memoryObject{
+		data: data,
+		info: newObjectInfo(objectName, int64(len(data)), etag, time.Now(), upload.options),
+		tags: copyTags(upload.options.UserTags),
+	}
+	delete(b.uploads, uploadID)
+	return nil
+}
+
+func (b memoryBackend) AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.upload(bucketName, objectName, uploadID); err != nil {
+		return err
+	}
+	delete(b.uploads, uploadID)
+	return nil
+}
+
+func (b memoryBackend) ListMultipartUploads(ctx context.Context, bucketName, objectPrefix string) (uploads []ObjectMultipartInfo, err error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if _, err = b.bucket(bucketName); err != nil {
+		return nil, err
+	}
+	for uploadID, upload := range b.uploads {
+		if upload.bucketName != bucketName || !strings.HasPrefix(upload.objectName, objectPrefix) {
+			continue
+		}
+		info := ObjectMultipartInfo{Key: upload.objectName, UploadID: uploadID, Initiated: upload.initiated}
+		for _, part := range upload.parts {
+			info.Size += part.info.Size
+		}
+		uploads = append(uploads, info)
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].Key < uploads[j].Key
+	})
+	return uploads, nil
+}
+
+func (b memoryBackend) ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string) (parts []ObjectPart, err error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	upload, err := b.upload(bucketName, objectName, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	for partNumber, part := range upload.parts {
+		parts = append(parts, ObjectPart{
+			PartNumber:   partNumber,
+			ETag:         part.info.ETag,
+			Size:         part.info.Size,
+			LastModified: part.info.LastModified,
+		})
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	return parts, nil
+}
+
+func (b memoryBackend) PresignGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error) {
+	return nil, errNotImplemented("Presigned URL")
+}
+
+func (b memoryBackend) PresignPutObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error) {
+	return nil, errNotImplemented("Presigned URL")
+}
@@ -0,0 +1,208 @@
+package space
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// copyHeaders replacing metadata and tags of the source object with the ones in `options`.
+func copyHeaders(options PutObjectOptions) map[string]string {
+	headers := map[string]string{
+		"X-Amz-Metadata-Directive": "REPLACE",
+		"X-Amz-Tagging-Directive":  "REPLACE",
+	}
+	for key, values := range options.Header() {
+		headers[key] = strings.Join(values, ",")
+	}
+	return headers
+}
+
+// minioBackend talks to an S3 compatible service, e.g. DigitalOcean Spaces or MinIO.
+type minioBackend struct {
+	client *minio.Client
+}
+
+// NewMinioBackend from a client created via `minio.New`.
+func NewMinioBackend(client *minio.Client) Backend {
+	return minioBackend{client: client}
+}
+
+// SetAppInfo adds custom application details to User-Agent.
+func (b minioBackend) SetAppInfo(appName, appVersion string) {
+	b.client.SetAppInfo(appName, appVersion)
+}
+
+func (b minioBackend) ListBuckets(ctx context.Context) ([]BucketInfo, error) {
+	return b.client.ListBucketsWithContext(ctx)
+}
+
+func (b minioBackend) ListObjects(ctx context.Context, bucketName, objectPrefix string, recursive bool) (objects []ObjectInfo, err error) {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	objectCh := b.client.ListObjectsV2(bucketName, objectPrefix, recursive, doneCh)
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, err
+}
+
+func (b minioBackend) ListObjectsPage(ctx context.Context, bucketName string, options ListOptions, token string) (objects []ObjectInfo, next string, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	delimiter := "/"
+	if options.Recursive {
+		delimiter = ""
+	}
+
+	result, err := minio.Core{Client: b.client}.ListObjectsV2(bucketName, options.Prefix, token, false, delimiter, options.pageSize(), options.StartAfter)
+	if err != nil {
+		return nil, "", err
+	}
+	objects = result.Contents
+	for _, prefix := range result.CommonPrefixes {
+		objects = append(objects, ObjectInfo{Key: prefix.Prefix})
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	if result.IsTruncated {
+		next = result.NextContinuationToken
+	}
+	return objects, next, nil
+}
+
+func (b minioBackend) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, options PutObjectOptions) (int64, error) {
+	return b.client.PutObjectWithContext(ctx, bucketName, objectName, reader, objectSize, options)
+}
+
+func (b minioBackend) GetObject(ctx context.Context, bucketName, objectName string, options GetObjectOptions) (Object, error) {
+	object, err := b.client.GetObjectWithContext(ctx, bucketName, objectName, options)
+	if err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func (b minioBackend) StatObject(ctx context.Context, bucketName, objectName string, options StatObjectOptions) (ObjectInfo, error) {
+	return b.client.StatObjectWithContext(ctx, bucketName, objectName, options)
+}
+
+func (b minioBackend) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	return b.client.RemoveObject(bucketName, objectName)
+}
+
+func (b minioBackend) RemoveObjects(ctx context.Context, bucketName string, objectNames []string) (errs []RemoveObjectError) {
+	objectsCh := make(chan string)
+
+	go func() {
+		defer close(objectsCh)
+		for _, name := range objectNames {
+			objectsCh <- name
+		}
+	}()
+
+	for rErr := range b.client.RemoveObjectsWithContext(ctx, bucketName, objectsCh) {
+		errs = append(errs, rErr)
+	}
+	return errs
+}
+
+func (b minioBackend) PutObjectTagging(ctx context.Context, bucketName, objectName string, tags map[string]string) error {
+	return b.client.PutObjectTaggingWithContext(ctx, bucketName, objectName, tags)
+}
+
+func (b minioBackend) GetObjectTagging(ctx context.Context, bucketName, objectName string) (map[string]string, error) {
+	text, err := b.client.GetObjectTaggingWithContext(ctx, bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalTags(text)
+}
+
+func (b minioBackend) RemoveObjectTagging(ctx context.Context, bucketName, objectName string) error {
+	return b.client.RemoveObjectTaggingWithContext(ctx, bucketName, objectName)
+}
+
+func (b minioBackend) CopyObject(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string, options PutObjectOptions) error {
+	_, err := minio.Core{Client: b.client}.CopyObjectWithContext(ctx, srcBucket, srcObject, dstBucket, dstObject, copyHeaders(options))
+	return err
+}
+
+func (b minioBackend) NewMultipartUpload(ctx context.Context, bucketName, objectName string, options PutObjectOptions) (uploadID string, err error) {
+	return minio.Core{Client: b.client}.NewMultipartUpload(bucketName, objectName, options)
+}
+
+func (b minioBackend) PutObjectPart(ctx context.Context, bucketName, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (ObjectPart, error) {
+	return minio.Core{Client: b.client}.PutObjectPartWithContext(ctx, bucketName, objectName, uploadID, partNumber, reader, partSize, "", "", nil)
+}
+
+func (b minioBackend) CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart) error {
+	_, err := minio.Core{Client: b.client}.CompleteMultipartUploadWithContext(ctx, bucketName, objectName, uploadID, parts)
+	return err
+}
+
+func (b minioBackend) AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error {
+	return minio.Core{Client: b.client}.AbortMultipartUploadWithContext(ctx, bucketName, objectName, uploadID)
+}
+
+func (b minioBackend) ListMultipartUploads(ctx context.Context, bucketName, objectPrefix string) (uploads []ObjectMultipartInfo, err error) {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	for upload := range b.client.ListIncompleteUploads(bucketName, objectPrefix, true, doneCh) {
+		if upload.Err != nil {
+			return nil, upload.Err
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
+
+func (b minioBackend) ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string) (parts []ObjectPart, err error) {
+	core := minio.Core{Client: b.client}
+	marker := 0
+	for {
+		result, err := core.ListObjectParts(bucketName, objectName, uploadID, marker, 1000)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, result.ObjectParts...)
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+func (b minioBackend) CopyObjectPart(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject, uploadID string, partNumber int, offset, length int64) (CompletePart, error) {
+	return minio.Core{Client: b.client}.CopyObjectPartWithContext(ctx, srcBucket, srcObject, dstBucket, dstObject, uploadID, partNumber, offset, length, nil)
+}
+
+func (b minioBackend) PresignGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error) {
+	return b.client.PresignedGetObject(bucketName, objectName, expires, url.Values{})
+}
+
+func (b minioBackend) PresignPutObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error) {
+	return b.client.PresignedPutObject(bucketName, objectName, expires)
+}
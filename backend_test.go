@@ -0,0 +1,199 @@
+package space_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lebenasa/space"
+	"github.com/minio/minio-go/v6"
+)
+
+func testBackend(t *testing.T, b space.Backend) {
+	ctx := context.Background()
+	bucket := "test.bucket"
+
+	buckets, err := b.ListBuckets(ctx)
+	if err != nil || len(buckets) != 1 || buckets[0].Name != bucket {
+		t.Errorf("list buckets got %v, %v, want %v", buckets, err, bucket)
+	}
+
+	content := "test content"
+	objectNames := []string{"a.txt", "foo/b.txt", "foo/bar/c.txt"}
+	for _, name := range objectNames {
+		n, err := b.PutObject(ctx, bucket, name, strings.NewReader(content), int64(len(content)), space.PutObjectOptions{
+			UserMetadata: map[string]string{"foo": "bar"},
+			UserTags:     map[string]string{"env": "test"},
+		})
+		if err != nil || n != int64(len(content)) {
+			t.Errorf("put %v got %v, %v", name, n, err)
+		}
+	}
+
+	if _, err = b.PutObject(ctx, "missing.bucket", "a.txt", strings.NewReader(content), -1, space.PutObjectOptions{}); minio.ToErrorResponse(err).Code != "NoSuchBucket" {
+		t.Errorf("put to missing bucket got %v, want NoSuchBucket", err)
+	}
+
+	objects, err := b.ListObjects(ctx, bucket, "", true)
+	if err != nil || len(objects) != 3 {
+		t.Errorf("list recursive got %v, %v, want 3 objects", objects, err)
+	}
+	objects, err = b.ListObjects(ctx, bucket, "foo/", false)
+	if err != nil || len(objects) != 2 || objects[0].Key != "foo/b.txt" || objects[1].Key != "foo/bar/" {
+		t.Errorf("list non-recursive got %v, %v, want foo/b.txt and foo/bar/", objects, err)
+	}
+
+	page, next, err := b.ListObjectsPage(ctx, bucket, space.ListOptions{Recursive: true, PageSize: 2}, "")
+	if err != nil || len(page) != 2 || page[0].Key != "a.txt" || next == "" {
+		t.Errorf("list page 1 got %v, %v, %v, want 2 objects and next token", page, next, err)
+	}
+	page, next, err = b.ListObjectsPage(ctx, bucket, space.ListOptions{Recursive: true, PageSize: 2}, next)
+	if err != nil || len(page) != 1 || page[0].Key != "foo/bar/c.txt" || next != "" {
+		t.Errorf("list page 2 got %v, %v, %v, want foo/bar/c.txt on last page", page, next, err)
+	}
+	page, _, err = b.ListObjectsPage(ctx, bucket, space.ListOptions{StartAfter: "a.txt"}, "")
+	if err != nil || len(page) != 1 || page[0].Key != "foo/" {
+		t.Errorf("list page after a.txt got %v, %v, want foo/", page, err)
+	}
+
+	info, err := b.StatObject(ctx, bucket, "foo/b.txt", space.StatObjectOptions{})
+	if err != nil {
+		t.Errorf("stat got %v", err)
+	}
+	if info.Size != int64(len(content)) || info.ETag != "9473fdd0d880a43c21b7778d34872157" {
+		t.Errorf("stat got size %v etag %v", info.Size, info.ETag)
+	}
+	if info.UserMetadata["Foo"] != "bar" {
+		t.Errorf("stat got metadata %v, want Foo: bar", info.UserMetadata)
+	}
+	if _, err = b.StatObject(ctx, bucket, "missing.txt", space.StatObjectOptions{}); minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		t.Errorf("stat missing got %v, want NoSuchKey", err)
+	}
+
+	object, err := b.GetObject(ctx, bucket, "a.txt", space.GetObjectOptions{})
+	if err != nil {
+		t.Fatalf("get got %v", err)
+	}
+	data, err := ioutil.ReadAll(object)
+	object.Close()
+	if err != nil || string(data) != content {
+		t.Errorf("get got %v, %v, want %v", string(data), err, content)
+	}
+
+	tags, err := b.GetObjectTagging(ctx, bucket, "a.txt")
+	if err != nil || tags["env"] != "test" {
+		t.Errorf("get tags got %v, %v, want env: test", tags, err)
+	}
+	if err = b.PutObjectTagging(ctx, bucket, "a.txt", map[string]string{"foo": "bar"}); err != nil {
+		t.Errorf("put tags got %v", err)
+	}
+	if tags, _ = b.GetObjectTagging(ctx, bucket, "a.txt"); len(tags) != 1 || tags["foo"] != "bar" {
+		t.Errorf("put tags got %v, want foo: bar", tags)
+	}
+	if err = b.RemoveObjectTagging(ctx, bucket, "a.txt"); err != nil {
+		t.Errorf("remove tags got %v", err)
+	}
+	if tags, _ = b.GetObjectTagging(ctx, bucket, "a.txt"); len(tags) != 0 {
+		t.Errorf("remove tags got %v, want no tags", tags)
+	}
+
+	err = b.CopyObject(ctx, bucket, "foo/b.txt", bucket, "foo/copy.txt", space.PutObjectOptions{
+		ContentType:  "text/plain",
+		UserMetadata: map[string]string{"foo": "baz"},
+		UserTags:     map[string]string{"copied": "yes"},
+	})
+	if err != nil {
+		t.Errorf("copy got %v", err)
+	}
+	info, _ = b.StatObject(ctx, bucket, "foo/copy.txt", space.StatObjectOptions{})
+	if info.Size != int64(len(content)) || info.ContentType != "text/plain" || info.UserMetadata["Foo"] != "baz" {
+		t.Errorf("copy got size %v content type %v metadata %v", info.Size, info.ContentType, info.UserMetadata)
+	}
+	if tags, _ = b.GetObjectTagging(ctx, bucket, "foo/copy.txt"); len(tags) != 1 || tags["copied"] != "yes" {
+		t.Errorf("copy got tags %v, want copied: yes", tags)
+	}
+	if err = b.CopyObject(ctx, bucket, "missing.txt", bucket, "foo/copy.txt", space.PutObjectOptions{}); minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		t.Errorf("copy missing got %v, want NoSuchKey", err)
+	}
+	objectNames = append(objectNames, "foo/copy.txt")
+
+	uploadID, err := b.NewMultipartUpload(ctx, bucket, "foo/multipart.txt", space.PutObjectOptions{})
+	if err != nil {
+		t.Fatalf("new multipart upload got %v", err)
+	}
+	parts := []space.CompletePart{}
+	for i, chunk := range []string{"test ", "content"} {
+		part, err := b.PutObjectPart(ctx, bucket, "foo/multipart.txt", uploadID, i+1, strings.NewReader(chunk), int64(len(chunk)))
+		if err != nil {
+			t.Errorf("put part %v got %v", i+1, err)
+		}
+		parts = append(parts, space.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	if err = b.CompleteMultipartUpload(ctx, bucket, "foo/multipart.txt", uploadID, parts); err != nil {
+		t.Errorf("complete multipart upload got %v", err)
+	}
+	if info, _ = b.StatObject(ctx, bucket, "foo/multipart.txt", space.StatObjectOptions{}); info.Size != int64(len(content)) || !strings.HasSuffix(info.ETag, "-2") {
+		t.Errorf("stat multipart got size %v etag %v", info.Size, info.ETag)
+	}
+	objectNames = append(objectNames, "foo/multipart.txt")
+
+	multipartETag := info.ETag
+	uploadID, _ = b.NewMultipartUpload(ctx, bucket, "foo/part-copy.txt", space.PutObjectOptions{})
+	parts = nil
+	for i, r := range [][2]int64{{0, 5}, {5, 7}} {
+		part, err := b.CopyObjectPart(ctx, bucket, "foo/multipart.txt", bucket, "foo/part-copy.txt", uploadID, i+1, r[0], r[1])
+		if err != nil {
+			t.Errorf("copy part %v got %v", i+1, err)
+		}
+		parts = append(parts, part)
+	}
+	if err = b.CompleteMultipartUpload(ctx, bucket, "foo/part-copy.txt", uploadID, parts); err != nil {
+		t.Errorf("complete part copy got %v", err)
+	}
+	if info, _ = b.StatObject(ctx, bucket, "foo/part-copy.txt", space.StatObjectOptions{}); info.Size != int64(len(content)) || info.ETag != multipartETag {
+		t.Errorf("stat part copy got size %v etag %v, want etag %v", info.Size, info.ETag, multipartETag)
+	}
+	objectNames = append(objectNames, "foo/part-copy.txt")
+
+	uploadID, _ = b.NewMultipartUpload(ctx, bucket, "aborted.txt", space.PutObjectOptions{})
+	if err = b.AbortMultipartUpload(ctx, bucket, "aborted.txt", uploadID); err != nil {
+		t.Errorf("abort multipart upload got %v", err)
+	}
+	if err = b.CompleteMultipartUpload(ctx, bucket, "aborted.txt", uploadID, nil); minio.ToErrorResponse(err).Code != "NoSuchUpload" {
+		t.Errorf("complete aborted upload got %v, want NoSuchUpload", err)
+	}
+
+	if err = b.RemoveObject(ctx, bucket, "a.txt"); err != nil {
+		t.Errorf("remove got %v", err)
+	}
+	if errs := b.RemoveObjects(ctx, bucket, objectNames[1:]); len(errs) != 0 {
+		t.Errorf("remove objects got %v", errs)
+	}
+	if objects, _ = b.ListObjects(ctx, bucket, "", true); len(objects) != 0 {
+		t.Errorf("list after remove got %v, want empty", objects)
+	}
+}
+
+func TestMemoryBackend(t *testing.T) {
+	testBackend(t, space.NewMemoryBackend("test.bucket"))
+}
+
+func TestLocalBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "space-local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, err := space.NewLocalBackend(dir, "test.bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBackend(t, b)
+
+	if _, err = b.PutObject(context.Background(), "test.bucket", "../escape.txt", strings.NewReader(""), 0, space.PutObjectOptions{}); err == nil {
+		t.Error("put outside bucket got no error, want error")
+	}
+}
@@ -0,0 +1,163 @@
+package space
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"time"
+)
+
+// typedBackend translates errors of another backend into `*Error`, see `translateError`.
+type typedBackend struct {
+	backend Backend
+}
+
+// typed errors returned by `backend`.
+func typed(backend Backend) Backend {
+	if _, ok := backend.(typedBackend); ok {
+		return backend
+	}
+	return typedBackend{backend}
+}
+
+func (b typedBackend) SetAppInfo(appName, appVersion string) {
+	if backend, ok := b.backend.(interface{ SetAppInfo(string, string) }); ok {
+		backend.SetAppInfo(appName, appVersion)
+	}
+}
+
+func (b typedBackend) ListBuckets(ctx context.Context) ([]BucketInfo, error) {
+	buckets, err := b.backend.ListBuckets(ctx)
+	return buckets, translateError(err)
+}
+
+func (b typedBackend) ListObjects(ctx context.Context, bucketName, objectPrefix string, recursive bool) ([]ObjectInfo, error) {
+	objects, err := b.backend.ListObjects(ctx, bucketName, objectPrefix, recursive)
+	return objects, translateError(err)
+}
+
+func (b typedBackend) ListObjectsPage(ctx context.Context, bucketName string, options ListOptions, token string) ([]ObjectInfo, string, error) {
+	objects, next, err := b.backend.ListObjectsPage(ctx, bucketName, options, token)
+	return objects, next, translateError(err)
+}
+
+func (b typedBackend) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, options PutObjectOptions) (int64, error) {
+	n, err := b.backend.PutObject(ctx, bucketName, objectName, reader, objectSize, options)
+	return n, translateError(err)
+}
+
+func (b typedBackend) GetObject(ctx context.Context, bucketName, objectName string, options GetObjectOptions) (Object, error) {
+	object, err := b.backend.GetObject(ctx, bucketName, objectName, options)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return typedObject{object}, nil
+}
+
+func (b typedBackend) StatObject(ctx context.Context, bucketName, objectName string, options StatObjectOptions) (ObjectInfo, error) {
+	info, err := b.backend.StatObject(ctx, bucketName, objectName, options)
+	return info, translateError(err)
+}
+
+func (b typedBackend) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	return translateError(b.backend.RemoveObject(ctx, bucketName, objectName))
+}
+
+func (b typedBackend) RemoveObjects(ctx context.Context, bucketName string, objectNames []string) []RemoveObjectError {
+	rErrs := b.backend.RemoveObjects(ctx, bucketName, objectNames)
+	for i := range rErrs {
+		rErrs[i].Err = translateError(rErrs[i].Err)
+	}
+	return rErrs
+}
+
+func (b typedBackend) PutObjectTagging(ctx context.Context, bucketName, objectName string, tags map[string]string) error {
+	return translateError(b.backend.PutObjectTagging(ctx, bucketName, objectName, tags))
+}
+
+func (b typedBackend) GetObjectTagging(ctx context.Context, bucketName, objectName string) (map[string]string, error) {
+	tags, err := b.backend.GetObjectTagging(ctx, bucketName, objectName)
+	return tags, translateError(err)
+}
+
+func (b typedBackend) RemoveObjectTagging(ctx context.Context, bucketName, objectName string) error {
+	return translateError(b.backend.RemoveObjectTagging(ctx, bucketName, objectName))
+}
+
+func (b typedBackend) CopyObject(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string, options PutObjectOptions) error {
+	return translateError(b.backend.CopyObject(ctx, srcBucket, srcObject, dstBucket, dstObject, options))
+}
+
+func (b typedBackend) NewMultipartUpload(ctx context.Context, bucketName, objectName string, options PutObjectOptions) (string, error) {
+	uploadID, err := b.backend.NewMultipartUpload(ctx, bucketName, objectName, options)
+	return uploadID, translateError(err)
+}
+
+func (b typedBackend) PutObjectPart(ctx context.Context, bucketName, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (ObjectPart, error) {
+	part, err := b.backend.PutObjectPart(ctx, bucketName, objectName, uploadID, partNumber, reader, partSize)
+	return part, translateError(err)
+}
+
+func (b typedBackend) CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart) error {
+	return translateError(b.backend.CompleteMultipartUpload(ctx, bucketName, objectName, uploadID, parts))
+}
+
+func (b typedBackend) AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error {
+	return translateError(b.backend.AbortMultipartUpload(ctx, bucketName, objectName, uploadID))
+}
+
+func (b typedBackend) ListMultipartUploads(ctx context.Context, bucketName, objectPrefix string) ([]ObjectMultipartInfo, error) {
+	uploads, err := b.backend.ListMultipartUploads(ctx, bucketName, objectPrefix)
+	return uploads, translateError(err)
+}
+
+func (b typedBackend) ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string) ([]ObjectPart, error) {
+	parts, err := b.backend.ListObjectParts(ctx, bucketName, objectName, uploadID)
+	return parts, translateError(err)
+}
+
+func (b typedBackend) CopyObjectPart(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject, uploadID string, partNumber int, offset, length int64) (CompletePart, error) {
+	part, err := b.backend.CopyObjectPart(ctx, srcBucket, srcObject, dstBucket, dstObject, uploadID, partNumber, offset, length)
+	return part, translateError(err)
+}
+
+func (b typedBackend) PresignGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error) {
+	u, err := b.backend.PresignGetObject(ctx, bucketName, objectName, expires)
+	return u, translateError(err)
+}
+
+func (b typedBackend) PresignPutObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error) {
+	u, err := b.backend.PresignPutObject(ctx, bucketName, objectName, expires)
+	return u, translateError(err)
+}
+
+// typedObject translates errors of an open object, S3 objects are only requested when first read.
+type typedObject struct {
+	Object
+}
+
+func (o typedObject) Read(p []byte) (int, error) {
+	n, err := o.Object.Read(p)
+	if err == io.EOF {
+		return n, err
+	}
+	return n, translateError(err)
+}
+
+func (o typedObject) ReadAt(p []byte, off int64) (int, error) {
+	n, err := o.Object.ReadAt(p, off)
+	if err == io.EOF {
+		return n, err
+	}
+	return n, translateError(err)
+}
+
+func (o typedObject) Seek(offset int64, whence int) (int64, error) {
+	n, err := o.Object.Seek(offset, whence)
+	return n, translateError(err)
+}
+
+func (o typedObject) Stat() (ObjectInfo, error) {
+	info, err := o.Object.Stat()
+	return info, translateError(err)
+}
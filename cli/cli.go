@@ -1,40 +1,127 @@
 package cli
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"path"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/lebenasa/space"
+	"github.com/lebenasa/space/config"
+	"github.com/lebenasa/space/ignore"
 
-	"github.com/jedib0t/go-pretty/table"
 	"github.com/urfave/cli/v2"
 )
 
+// loadProfile selected by `--profile` from config file and environment, overridden by global flags.
+func loadProfile(c *cli.Context) (profile config.Profile, err error) {
+	cfg, err := config.Load(c.String("config"))
+	if err != nil {
+		return profile, err
+	}
+	profile, err = cfg.Select(c.String("profile"))
+	if err != nil {
+		return profile, err
+	}
+
+	envs, err := config.ParseEnvironments(c.String("environments"))
+	if err != nil {
+		return profile, err
+	}
+	// Explicit key and secret take precedence over every other credential source.
+	var sources []string
+	if c.String("key") != "" || c.String("secret") != "" {
+		sources = []string{config.CredentialsStatic}
+	}
+	return profile.Merge(config.Profile{
+		Credentials:  sources,
+		Endpoint:     c.String("endpoint"),
+		Key:          c.String("key"),
+		Secret:       c.String("secret"),
+		Insecure:     c.Bool("insecure"),
+		CACert:       c.String("ca-cert"),
+		ClientCert:   c.String("client-cert"),
+		ClientKey:    c.String("client-key"),
+		Addressing:   c.String("addressing"),
+		Region:       c.String("region"),
+		Environments: envs,
+	}), nil
+}
+
+// newSpace from selected profile, retrying failed operations as many times as global `--retries` flag.
+func newSpace(c *cli.Context) (s space.Space, err error) {
+	profile, err := loadProfile(c)
+	if err != nil {
+		return s, err
+	}
+	s, err = space.NewFromProfile(profile)
+	if err != nil {
+		return s, err
+	}
+	if c.Int("retries") < 0 {
+		return s, cli.Exit(fmt.Sprintf("Invalid retries %v, want 0 or more", c.Int("retries")), ExitUsage)
+	}
+	policy := space.DefaultRetryPolicy
+	policy.MaxAttempts = c.Int("retries") + 1
+	return s.WithRetry(policy), nil
+}
+
 func downloadAction(c *cli.Context) error {
 	objectName := c.Args().Get(0)
-	if objectName == "" {
+	if objectName == "" && !c.Bool("recursive") {
 		return cli.Exit("No Space object given.", 2)
 	}
-	fileName := filepath.Base(objectName)
+	fileName := path.Base(strings.TrimSuffix(objectName, "/"))
+	if objectName == "" {
+		fileName = "."
+	}
 	if c.String("output") != "" {
 		fileName = c.String("output")
 	}
 
-	env, err := handleEnvFlag(c.String("env"))
+	env, err := handleEnvFlag(c)
 	if err != nil {
 		return err
 	}
 
-	s, err := space.New()
+	s, err := newSpace(c)
 	if err != nil {
 		return err
 	}
 
-	err = s.DownloadFile(context.Background(), objectName, fileName, env)
+	r, err := newRenderer(c, "Object", "File", "Error")
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("recursive") {
+		if s, err = withFilters(c, s); err != nil {
+			return err
+		}
+		return pullFolder(c.Context, r, objectName, fileName, s.WithJobs(c.Int("jobs")), env)
+	}
+	if err = s.DownloadFile(c.Context, objectName, fileName, env); err != nil {
+		return err
+	}
+	r.Append(objectName, fileName, nil)
+	return r.Render()
+}
+
+func pullFolder(ctx context.Context, r *renderer, prefix, folder string, s space.Space, env string) error {
+	s = s.WithReport(func(result space.TransferResult) {
+		r.Append(result.ObjectName, result.Path, result.Err)
+	})
+
+	_, err := s.DownloadFolder(ctx, prefix, folder, env)
+	if rErr := r.Render(); err == nil {
+		err = rErr
+	}
 	return err
 }
 
@@ -47,109 +134,129 @@ func handleEnum(val string, enums []string) (value string, err error) {
 	return "", fmt.Errorf("Invalid argument %v, possible values: %v", val, enums)
 }
 
-func handleEnvFlag(val string) (string, error) {
-	return handleEnum(val, []string{
-		"dev",
-		"live",
-	})
+// handleEnvFlag checks `--env` against environments of selected profile, see `space env list`.
+func handleEnvFlag(c *cli.Context) (string, error) {
+	profile, err := loadProfile(c)
+	if err != nil {
+		return "", err
+	}
+	env := c.String("env")
+	if _, err = profile.Bucket(env); err != nil {
+		return "", invalidEnv(err)
+	}
+	return env, nil
+}
+
+// invalidEnv error exiting with `ExitInvalidEnv`.
+func invalidEnv(err error) error {
+	return &space.Error{Kind: space.ErrInvalidEnv, Err: err}
 }
 
-func listObjects(s space.Space, bucket, prefix string) error {
-	fmt.Printf("Listing objects from %v with prefix '%v'\n", bucket, prefix)
-	objects, err := s.ListObjects(bucket, prefix, true)
+func listObjects(c *cli.Context, s space.Space, bucket, prefix string) error {
+	r, err := newRenderer(c, "Object", "Size", "Last modified")
 	if err != nil {
 		return err
 	}
+	if r.format == "table" {
+		fmt.Printf("Listing objects from %v with prefix '%v'\n", bucket, prefix)
+	}
 
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Object", "Size", "Last modified"})
-	for _, object := range objects {
-		t.AppendRow([]interface{}{object.Key, object.Size, object.LastModified})
+	options := space.ListOptions{Prefix: prefix, Recursive: true}
+	err = s.WalkObjects(c.Context, bucket, options, func(object space.ObjectInfo) error {
+		r.Append(object.Key, object.Size, object.LastModified)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	t.SetStyle(table.StyleColoredBlueWhiteOnBlack)
-	t.Render()
-	return nil
+	return r.Render()
 }
 
-func listBuckets(s space.Space) error {
-	buckets, err := s.ListBuckets()
+func listBuckets(c *cli.Context, s space.Space) error {
+	r, err := newRenderer(c, "Bucket", "Created on")
+	if err != nil {
+		return err
+	}
+	buckets, err := s.ListBuckets(c.Context)
 	if err != nil {
 		return err
 	}
 
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Bucket", "Created on"})
 	for _, bucket := range buckets {
-		t.AppendRow([]interface{}{bucket.Name, bucket.CreationDate})
+		r.Append(bucket.Name, bucket.CreationDate)
 	}
-	t.SetStyle(table.StyleColoredBlueWhiteOnBlack)
-	t.Render()
-
-	return nil
+	return r.Render()
 }
 
 func listInternalAction(c *cli.Context) error {
 	bucket, prefix := parseBucketAndPrefix(c.Args().First())
 
-	s, err := space.New()
+	s, err := newSpace(c)
 	if err != nil {
 		return err
 	}
 
 	if bucket != "" {
-		return listObjects(s, bucket, prefix)
+		return listObjects(c, s, bucket, prefix)
 	}
 
-	return listBuckets(s)
+	return listBuckets(c, s)
 }
 
 func listAction(c *cli.Context) error {
-	env, err := handleEnvFlag(c.String("env"))
+	env, err := handleEnvFlag(c)
 	if err != nil {
 		return err
 	}
 
-	s, err := space.New()
+	s, err := newSpace(c)
 	if err != nil {
 		return err
 	}
 
-	prefix := c.Args().First()
-	objects, err := s.List(env, prefix)
-	if err != nil {
+	if s, err = withFilters(c, s); err != nil {
 		return err
 	}
 
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Object", "Size", "Last modified"})
-	for _, object := range objects {
-		t.AppendRow([]interface{}{object.Key, object.Size, object.LastModified})
+	r, err := newRenderer(c, "Object", "Size", "Last modified")
+	if err != nil {
+		return err
 	}
-	t.SetStyle(table.StyleColoredBlueWhiteOnBlack)
-	t.Render()
 
-	return nil
+	options := space.ListOptions{
+		Prefix:     c.Args().First(),
+		Recursive:  true,
+		StartAfter: c.String("start-after"),
+		PageSize:   c.Int("limit"),
+	}
+	count, limit := 0, c.Int("limit")
+	err = s.Walk(c.Context, env, options, func(object space.ObjectInfo) error {
+		if limit > 0 && count >= limit {
+			return space.ErrStopWalk
+		}
+		r.Append(object.Key, object.Size, object.LastModified)
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return r.Render()
 }
 
-func pushFolder(folder string, s space.Space, env string, prefix string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*60*time.Second)
-	defer cancel()
+func pushFolder(ctx context.Context, r *renderer, folder string, s space.Space, env string, prefix string) error {
+	s = s.WithReport(func(result space.TransferResult) {
+		r.Append(result.Path, result.ObjectName, result.Err)
+	})
 
-	// TODO: verify uploaded files
-	objectNames, err := s.UploadFolder(ctx, folder, env, prefix)
-	for _, name := range objectNames {
-		fmt.Println(name)
+	_, err := s.UploadFolder(ctx, folder, env, prefix)
+	if rErr := r.Render(); err == nil {
+		err = rErr
 	}
 	return err
 }
 
-func pushFile(fileName string, s space.Space, env string, prefix string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*60*time.Second)
-	defer cancel()
-
+func pushFile(ctx context.Context, r *renderer, fileName string, s space.Space, env string, prefix string) error {
 	fi, err := os.Stat(fileName)
 	if err != nil {
 		return err
@@ -159,35 +266,65 @@ func pushFile(fileName string, s space.Space, env string, prefix string) error {
 		return fmt.Errorf("%v is a directory, push with --recursive flag", fileName)
 	}
 
-	// TODO: verify uploaded file
-	objectName, err := s.UploadFile(ctx, fileName, env, prefix)
-	fmt.Println(objectName)
-	return err
+	objectName, err := s.Upload(ctx, fileName, env, prefix)
+	if err != nil {
+		if fi.Size() > space.BigFileThreshold {
+			fmt.Fprintln(os.Stderr, "Upload progress is kept, continue with --resume or clean up with `space uploads abort`.")
+		}
+		return err
+	}
+	r.Append(fileName, objectName, nil)
+	return r.Render()
 }
 
 func pushAction(c *cli.Context) error {
-	env, err := handleEnvFlag(c.String("env"))
+	env, err := handleEnvFlag(c)
+	if err != nil {
+		return err
+	}
+
+	s, err := newSpace(c)
 	if err != nil {
 		return err
 	}
 
-	s, err := space.New()
+	stateDir, err := space.DefaultStateDir()
 	if err != nil {
 		return err
 	}
 
-	s = s.WithTags(parseTags(c.String("tags")))
+	s = s.WithStateDir(stateDir).
+		WithTags(parseTags(c.String("tags"))).
+		WithJobs(c.Int("jobs")).
+		WithPartSize(c.Int64("part-size") << 20).
+		WithResume(c.Bool("resume")).
+		WithVerify(c.Bool("verify")).
+		WithIgnore(parseIgnore(c.StringSlice("exclude"), c.StringSlice("include")))
 
 	fp := c.Args().Get(0)
 	if fp == "" {
 		return fmt.Errorf("Invalid file/folder: '%v'", fp)
 	}
 
+	r, err := newRenderer(c, "File", "Object", "Error")
+	if err != nil {
+		return err
+	}
+
 	prefix := c.String("prefix")
 	if c.Bool("recursive") {
-		return pushFolder(fp, s, env, prefix)
+		return pushFolder(c.Context, r, fp, s, env, prefix)
 	}
-	return pushFile(fp, s, env, prefix)
+	return pushFile(c.Context, r, fp, s, env, prefix)
+}
+
+// parseIgnore patterns where includes override excludes and `.spaceignore` files.
+func parseIgnore(excludes, includes []string) *ignore.Matcher {
+	matcher := ignore.New(excludes...)
+	for _, include := range includes {
+		matcher.Add("", "!"+include)
+	}
+	return matcher
 }
 
 func parseBucketAndPrefix(text string) (bucket, prefix string) {
@@ -223,12 +360,12 @@ func parseTags(text string) (tags map[string]string) {
 }
 
 func removeAction(c *cli.Context) error {
-	env, err := handleEnvFlag(c.String("env"))
+	env, err := handleEnvFlag(c)
 	if err != nil {
 		return err
 	}
 
-	s, err := space.New()
+	s, err := newSpace(c)
 	if err != nil {
 		return err
 	}
@@ -238,40 +375,251 @@ func removeAction(c *cli.Context) error {
 		objectNames[i] = c.Args().Get(i)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*60*time.Second)
-	defer cancel()
+	r, err := newRenderer(c, "Object", "Status", "Error")
+	if err != nil {
+		return err
+	}
+
+	if s, err = withFilters(c, s); err != nil {
+		return err
+	}
 
-	return s.RemoveFiles(ctx, env, objectNames)
+	ctx := c.Context
+	if c.Bool("recursive") {
+		return removePrefixes(ctx, c, r, s, env, objectNames)
+	}
+
+	profile, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	prompt := fmt.Sprintf("Remove %v objects from %v?", len(objectNames), env)
+	if profile.Protected(env) && !confirm(prompt, env, true, false) {
+		return fmt.Errorf("Aborted, nothing removed")
+	}
+
+	removed, err := s.RemoveFiles(ctx, env, objectNames)
+	return renderRemoved(r, removed, err)
 }
 
-// Run using arguments from `argv`.
+// renderRemoved objects followed by failed ones if removal partially failed.
+func renderRemoved(r *renderer, removed []string, err error) error {
+	bErr := &space.BatchError{}
+	if err != nil && !errors.As(err, &bErr) {
+		return err
+	}
+
+	for _, objectName := range removed {
+		r.Append(objectName, "removed", nil)
+	}
+	if err != nil {
+		for _, failed := range bErr.Failed {
+			r.Append(failed.ObjectName, "failed", failed.Message)
+		}
+	}
+	if rErr := r.Render(); err == nil {
+		err = rErr
+	}
+	return err
+}
+
+// folderPrefix treats `prefix` as a folder, so "foo" doesn't match "foobar.txt".
+func folderPrefix(prefix string) string {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
+
+// removePrefixes removes every object under given prefixes selected by filters, after confirmation.
+func removePrefixes(ctx context.Context, c *cli.Context, r *renderer, s space.Space, env string, prefixes []string) error {
+	if len(prefixes) == 0 {
+		return fmt.Errorf("No Space prefix given")
+	}
+
+	objectNames, size := []string{}, int64(0)
+	for _, prefix := range prefixes {
+		err := s.Walk(ctx, env, space.ListOptions{Prefix: folderPrefix(prefix), Recursive: true}, func(object space.ObjectInfo) error {
+			objectNames = append(objectNames, object.Key)
+			size += object.Size
+			if c.Bool("dry-run") {
+				r.Append(object.Key, "planned", nil)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	summary := fmt.Sprintf("%v objects (%v) from %v", len(objectNames), formatSize(size), env)
+	if c.Bool("dry-run") {
+		fmt.Fprintf(os.Stderr, "Would remove %v\n", summary)
+		return r.Render()
+	}
+	if len(objectNames) == 0 {
+		fmt.Fprintln(os.Stderr, "Nothing to remove")
+		return r.Render()
+	}
+
+	profile, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	if !confirm(fmt.Sprintf("Remove %v?", summary), env, profile.Protected(env), c.Bool("yes")) {
+		return fmt.Errorf("Aborted, nothing removed")
+	}
+
+	removed, err := s.WithFilter().RemoveFiles(ctx, env, objectNames)
+	return renderRemoved(r, removed, err)
+}
+
+// confirm asks user on stderr to answer "y", skipped with `yes`. Protected environments are always confirmed
+// by typing their name, see `space env add --protected`.
+func confirm(prompt, env string, protected, yes bool) bool {
+	answer := "y"
+	if protected {
+		answer = env
+		prompt += fmt.Sprintf(" Type '%v' to confirm:", env)
+	} else if yes {
+		return true
+	} else {
+		prompt += " [y/N]"
+	}
+
+	fmt.Fprintf(os.Stderr, "%v ", prompt)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.EqualFold(strings.TrimSpace(line), answer)
+}
+
+// Exit codes of `space` command, see `ExitCode`.
+const (
+	ExitOK             = 0
+	ExitFailure        = 1
+	ExitUsage          = 2
+	ExitPartialFailure = 3
+	ExitNotFound       = 4
+	ExitBucketNotFound = 5
+	ExitAccessDenied   = 6
+	ExitInvalidEnv     = 7
+	ExitConflict       = 8
+	ExitTimeout        = 124
+	ExitInterrupted    = 130
+)
+
+// exitCodes of error kinds returned by `space` package.
+var exitCodes = []struct {
+	kind error
+	code int
+}{
+	{space.ErrNotFound, ExitNotFound},
+	{space.ErrBucketNotFound, ExitBucketNotFound},
+	{space.ErrAccessDenied, ExitAccessDenied},
+	{space.ErrInvalidEnv, ExitInvalidEnv},
+	{space.ErrConflict, ExitConflict},
+	{context.DeadlineExceeded, ExitTimeout},
+	{context.Canceled, ExitInterrupted},
+}
+
+// exitCodesHelp documents exit codes in `space --help`.
+const exitCodesHelp = `Exit codes:
+   0    success
+   1    failure
+   2    invalid usage
+   3    partial failure, some objects failed while others succeeded
+   4    object not found
+   5    bucket not found
+   6    access denied, check credentials
+   7    invalid environment
+   8    conflict with current state of the bucket or object
+   124  timed out, see --timeout
+   130  interrupted by SIGINT or SIGTERM`
+
+// ExitCode for an error returned by `Run`, see `exitCodesHelp`. Batch operations where only some
+// objects failed exit with `ExitPartialFailure`, errors of known kind like `space.ErrNotFound` have their own code.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var bErr *space.BatchError
+	var tErr *space.TransferError
+	if errors.As(err, &bErr) || errors.As(err, &tErr) {
+		return ExitPartialFailure
+	}
+	for _, c := range exitCodes {
+		if errors.Is(err, c.kind) {
+			return c.code
+		}
+	}
+	var exitErr cli.ExitCoder
+	if errors.As(err, &exitErr) && exitErr.ExitCode() != ExitOK {
+		return exitErr.ExitCode()
+	}
+	return ExitFailure
+}
+
+// signalContext cancelled on SIGINT or SIGTERM, call `stop` once done. Another signal after the first one
+// is no longer caught, so it terminates the process right away.
+func signalContext(parent context.Context) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// Run using arguments from `argv`. Commands run until done, SIGINT or SIGTERM cancels them.
+// Failed requests are only retried as many times as `--retries`, see `space.DisableClientRetries`.
 func Run(argv []string) (err error) {
+	space.DisableClientRetries()
+
 	envFlag := cli.StringFlag{
 		Name:  "env",
 		Value: "dev",
-		Usage: "Specify Space environment",
+		Usage: "Specify Space environment, see `space env list`",
 	}
 
 	downloadCommand := cli.Command{
 		Name:      "pull",
 		Aliases:   []string{"download"},
 		Usage:     "Download file from Space",
-		ArgsUsage: "Space object's name",
-		Flags: []cli.Flag{
+		ArgsUsage: "Space object's name, or prefix with --recursive",
+		Flags: append([]cli.Flag{
 			&envFlag,
 			&cli.StringFlag{
 				Name:    "output",
 				Aliases: []string{"o"},
-				Usage:   "Output file, otherwise use object's name",
+				Usage:   "Output file (or folder with --recursive), otherwise use object's name",
 				Value:   "",
 			},
-		},
+			&cli.BoolFlag{
+				Name:    "recursive",
+				Aliases: []string{"r"},
+				Usage:   "Download every object under given prefix",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Usage:   "Number of concurrent downloads",
+				Value:   space.DefaultJobs,
+			},
+		}, filterFlags()...),
 		Action: downloadAction,
 	}
 
 	listInternalCommand := cli.Command{
 		Name:      "list-internal",
-		Usage:     "List available buckets or objects in Space. Not a good idea for production bucket.",
+		Usage:     "List available buckets or objects in Space.",
 		ArgsUsage: "If given, list all objects in {bucket}/{prefix}, otherwise list all buckets",
 		HideHelp:  true,
 		Hidden:    true,
@@ -285,9 +633,17 @@ func Run(argv []string) (err error) {
 		Name:      "list",
 		Usage:     "List available objects in Space.",
 		ArgsUsage: "Prefix",
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&envFlag,
-		},
+			&cli.IntFlag{
+				Name:  "limit",
+				Usage: "Stop after listing this many objects",
+			},
+			&cli.StringFlag{
+				Name:  "start-after",
+				Usage: "List objects whose name comes after this one, e.g. the last object of previous listing",
+			},
+		}, filterFlags()...),
 		Action: listAction,
 	}
 
@@ -304,6 +660,34 @@ func Run(argv []string) (err error) {
 				Usage:   "Upload a folder recursively",
 				Value:   false,
 			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Usage:   "Number of concurrent uploads with --recursive, or concurrent parts for big files",
+				Value:   space.DefaultJobs,
+			},
+			&cli.Int64Flag{
+				Name:  "part-size",
+				Usage: fmt.Sprintf("Part size in MiB for files larger than %v MiB, uploaded in parts", space.BigFileThreshold>>20),
+				Value: space.DefaultPartSize >> 20,
+			},
+			&cli.BoolFlag{
+				Name:  "verify",
+				Usage: "Compare uploaded files against their local checksum, SHA-256 is stored in object metadata",
+			},
+			&cli.BoolFlag{
+				Name:  "resume",
+				Usage: "Continue interrupted upload of big files instead of starting over",
+			},
+			&cli.StringSliceFlag{
+				Name:    "exclude",
+				Aliases: []string{"x"},
+				Usage:   "Skip files matching gitignore-style pattern with --recursive, in addition to .spaceignore and .git/",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include",
+				Usage: "Upload files matching gitignore-style pattern with --recursive, even if excluded, e.g. .git",
+			},
 			&cli.StringFlag{
 				Name:    "prefix",
 				Aliases: []string{"p"},
@@ -324,25 +708,389 @@ func Run(argv []string) (err error) {
 		Name:      "remove",
 		Aliases:   []string{"rm"},
 		Usage:     "Remove file(s) in Space",
-		ArgsUsage: "Files to be removed",
+		ArgsUsage: "Files to be removed, or prefixes with --recursive",
+		Flags: append([]cli.Flag{
+			&envFlag,
+			&cli.BoolFlag{
+				Name:    "recursive",
+				Aliases: []string{"r"},
+				Usage:   "Remove every object under given prefixes",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "List objects that would be removed with --recursive",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "Don't ask for confirmation with --recursive, except on protected environments",
+			},
+		}, filterFlags()...),
+		Action: removeAction,
+	}
+
+	duCommand := cli.Command{
+		Name:      "du",
+		Usage:     "Show number of objects and total size per prefix",
+		ArgsUsage: "Prefix",
+		Flags: append([]cli.Flag{
+			&envFlag,
+			&cli.IntFlag{
+				Name:    "depth",
+				Aliases: []string{"d"},
+				Usage:   "Number of prefix levels to show below given prefix",
+				Value:   1,
+			},
+			&cli.BoolFlag{
+				Name:    "human",
+				Aliases: []string{"H"},
+				Usage:   "Print sizes in human readable units, e.g. 1.5 GiB",
+			},
+			&cli.StringFlag{
+				Name:  "sort",
+				Usage: "Sort by prefix, size or objects (descending)",
+				Value: "prefix",
+			},
+		}, filterFlags()...),
+		Action: duAction,
+	}
+
+	copyFlags := append([]cli.Flag{
+		&envFlag,
+		&cli.StringFlag{
+			Name:  "to-env",
+			Usage: "Environment to copy to, otherwise --env",
+		},
+		&cli.StringFlag{
+			Name:  "bucket",
+			Usage: "Bucket to copy from instead of --env",
+		},
+		&cli.StringFlag{
+			Name:  "to-bucket",
+			Usage: "Bucket to copy to instead of --to-env",
+		},
+		&cli.BoolFlag{
+			Name:    "recursive",
+			Aliases: []string{"r"},
+			Usage:   "Copy every object under given prefix into destination prefix",
+		},
+		&cli.BoolFlag{
+			Name:  "verify",
+			Usage: "Compare copied objects against their source",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "List objects that would be copied",
+		},
+		&cli.IntFlag{
+			Name:    "jobs",
+			Aliases: []string{"j"},
+			Usage:   "Number of concurrent copies, or concurrent parts for objects over 5 GiB",
+			Value:   space.DefaultJobs,
+		},
+	}, filterFlags()...)
+
+	copyCommand := cli.Command{
+		Name:      "cp",
+		Aliases:   []string{"copy"},
+		Usage:     "Copy an object server side, or every object under a prefix with --recursive",
+		ArgsUsage: "Source and destination object (or prefix with --recursive), destination ending with / keeps source's name",
+		Flags:     copyFlags,
+		Action:    copyAction,
+	}
+
+	moveCommand := cli.Command{
+		Name:      "mv",
+		Aliases:   []string{"move"},
+		Usage:     "Move an object server side, or every object under a prefix with --recursive",
+		ArgsUsage: "Source and destination object (or prefix with --recursive), destination ending with / keeps source's name",
+		Flags:     copyFlags,
+		Action:    moveAction,
+	}
+
+	promoteCommand := cli.Command{
+		Name:      "promote",
+		Usage:     "Copy objects from an environment to another server side, e.g. once tested on dev",
+		ArgsUsage: "Prefix",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "from",
+				Usage: "Environment to promote from",
+				Value: "dev",
+			},
+			&cli.StringFlag{
+				Name:     "to",
+				Usage:    "Environment to promote to, e.g. live",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "verify",
+				Usage: "Compare promoted objects against their source",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "List objects that would be promoted",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "Don't ask for confirmation, except on protected environments",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Usage:   "Number of concurrent copies",
+				Value:   space.DefaultJobs,
+			},
+		}, filterFlags()...),
+		Action: promoteAction,
+	}
+
+	shareCommand := cli.Command{
+		Name:      "share",
+		Usage:     "Print a presigned URL to download an object without credentials, or to upload it with --upload",
+		ArgsUsage: "Space object's name",
 		Flags: []cli.Flag{
 			&envFlag,
+			&cli.DurationFlag{
+				Name:  "expires",
+				Usage: "URL expires after this long, e.g. 24h, limited per environment (see `space env add --max-share-expiry`)",
+				Value: 24 * time.Hour,
+			},
+			&cli.BoolFlag{
+				Name:  "upload",
+				Usage: "Share a URL to upload the object instead of downloading it",
+			},
+		},
+		Action: shareAction,
+	}
+
+	syncCommand := cli.Command{
+		Name:      "sync",
+		Usage:     "Transfer only new and changed files between a local folder and a prefix",
+		ArgsUsage: "Local folder and Space prefix",
+		Flags: append([]cli.Flag{
+			&envFlag,
+			&cli.BoolFlag{
+				Name:  "pull",
+				Usage: "Sync from Space prefix into local folder, otherwise from local folder into Space",
+			},
+			&cli.BoolFlag{
+				Name:  "delete",
+				Usage: "Remove files or objects missing from the source",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Print planned actions without transferring anything",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Usage:   "Number of concurrent transfers",
+				Value:   space.DefaultJobs,
+			},
+			&cli.StringSliceFlag{
+				Name:    "exclude",
+				Aliases: []string{"x"},
+				Usage:   "Skip files matching gitignore-style pattern, in addition to .spaceignore",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include",
+				Usage: "Sync files matching gitignore-style pattern, even if excluded",
+			},
+		}, filterFlags()...),
+		Action: syncAction,
+	}
+
+	treeCommand := cli.Command{
+		Name:      "tree",
+		Usage:     "Show objects under a prefix as a directory tree with per-directory totals",
+		ArgsUsage: "Prefix",
+		Flags: append([]cli.Flag{
+			&envFlag,
+			&cli.IntFlag{
+				Name:    "max-depth",
+				Aliases: []string{"L"},
+				Usage:   "Expand at most this many levels, deeper directories only show totals",
+			},
+		}, filterFlags()...),
+		Action: treeAction,
+	}
+
+	uploadsCommand := cli.Command{
+		Name:  "uploads",
+		Usage: "Inspect and clean up incomplete multipart uploads",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "list",
+				Aliases:   []string{"ls"},
+				Usage:     "List incomplete uploads",
+				ArgsUsage: "Prefix",
+				Flags: []cli.Flag{
+					&envFlag,
+				},
+				Action: uploadsListAction,
+			},
+			{
+				Name:      "abort",
+				Usage:     "Abort incomplete uploads of an object, or every upload under a prefix with --all",
+				ArgsUsage: "Object name (or prefix with --all) and optional upload ID",
+				Flags: []cli.Flag{
+					&envFlag,
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "Abort every incomplete upload whose object name starts with given prefix",
+					},
+				},
+				Action: uploadsAbortAction,
+			},
+		},
+	}
+
+	envCommand := cli.Command{
+		Name:  "env",
+		Usage: "Manage environments of selected profile, each maps to a bucket",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "list",
+				Aliases: []string{"ls"},
+				Usage:   "List environments with their bucket and settings",
+				Action:  envListAction,
+			},
+			{
+				Name:      "show",
+				Usage:     "Show bucket and settings of an environment",
+				ArgsUsage: "Environment",
+				Action:    envShowAction,
+			},
+			{
+				Name:      "add",
+				Usage:     "Add or update an environment in config file",
+				ArgsUsage: "Environment and bucket",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "protected",
+						Usage: "Require typing environment's name to confirm destructive commands, unset with --protected=false",
+					},
+					&cli.StringFlag{
+						Name:  "max-share-expiry",
+						Usage: fmt.Sprintf("Limit expiry of URLs shared with `space share`, e.g. 24h, at most %v", config.MaxShareExpiry),
+					},
+				},
+				Action: envAddAction,
+			},
+			{
+				Name:      "remove",
+				Aliases:   []string{"rm"},
+				Usage:     "Remove an environment from config file",
+				ArgsUsage: "Environment",
+				Action:    envRemoveAction,
+			},
 		},
-		Action: removeAction,
 	}
 
+	var cancelTimeout context.CancelFunc
 	app := &cli.App{
-		Name:  "space",
-		Usage: "Work with Space and assets",
+		Name:        "space",
+		Usage:       "Work with Space and assets",
+		Description: exitCodesHelp,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "Config file, otherwise use $SPACE_CONFIG or user's config dir",
+			},
+			&cli.StringFlag{
+				Name:  "profile",
+				Usage: "Named profile from config file, otherwise use $SPACE_PROFILE or default profile",
+			},
+			&cli.StringFlag{
+				Name:  "endpoint",
+				Usage: "Override Space endpoint, e.g. ny1.digitaloceanspaces.com, or file://{dir} to work offline",
+			},
+			&cli.StringFlag{
+				Name:  "key",
+				Usage: "Override Space access key",
+			},
+			&cli.StringFlag{
+				Name:  "secret",
+				Usage: "Override Space secret",
+			},
+			&cli.StringFlag{
+				Name:  "environments",
+				Usage: "Add or override environments, e.g. \"dev=dev.bucket,live=live.bucket\"",
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Usage: "Output format: " + strings.Join(outputFormats, ", "),
+				Value: outputFormats[0],
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Cancel the command if it takes longer, e.g. 10s or 2h, or never if 0",
+			},
+			&cli.IntFlag{
+				Name:  "retries",
+				Usage: "Retry failed requests this many times with exponential backoff",
+				Value: space.DefaultRetryPolicy.MaxAttempts - 1,
+			},
+			&cli.BoolFlag{
+				Name:  "insecure",
+				Usage: "Use plain HTTP instead of HTTPS, e.g. for local MinIO",
+			},
+			&cli.StringFlag{
+				Name:  "ca-cert",
+				Usage: "PEM bundle of trusted CA certificates besides system ones",
+			},
+			&cli.StringFlag{
+				Name:  "client-cert",
+				Usage: "PEM client certificate for TLS authentication, requires --client-key",
+			},
+			&cli.StringFlag{
+				Name:  "client-key",
+				Usage: "PEM private key of --client-cert",
+			},
+			&cli.StringFlag{
+				Name:  "addressing",
+				Usage: "Bucket addressing: " + strings.Join(config.Addressings, ", "),
+			},
+			&cli.StringFlag{
+				Name:  "region",
+				Usage: "Endpoint region, otherwise detected",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			if timeout := c.Duration("timeout"); timeout > 0 {
+				c.Context, cancelTimeout = context.WithTimeout(c.Context, timeout)
+			}
+			return nil
+		},
 		Commands: []*cli.Command{
+			&copyCommand,
 			&downloadCommand,
+			&duCommand,
+			&envCommand,
 			&listInternalCommand,
 			&listCommand,
+			&moveCommand,
+			&promoteCommand,
 			&pushCommand,
 			&removeCommand,
+			&shareCommand,
+			&syncCommand,
+			&treeCommand,
+			&uploadsCommand,
 		},
+		// Errors are returned to the caller, who exits with `ExitCode`.
+		ExitErrHandler: func(*cli.Context, error) {},
 	}
 
-	err = app.Run(argv)
+	ctx, stop := signalContext(context.Background())
+	defer stop()
+	defer func() {
+		if cancelTimeout != nil {
+			cancelTimeout()
+		}
+	}()
+	err = app.RunContext(ctx, argv)
 	return err
 }
@@ -1,14 +1,45 @@
 package cli_test
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/lebenasa/space"
 	"github.com/lebenasa/space/cli"
-	"github.com/lebenasa/space/service"
+	"github.com/lebenasa/space/config"
 )
 
+const devBucket = "dev.bucket"
+
+// TestMain runs every command against local directory backend, ignoring user's config file.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "space-cli-test")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	cfgPath := filepath.Join(dir, "config.json")
+	ioutil.WriteFile(cfgPath, []byte("{}"), 0600)
+
+	os.Setenv(config.EnvConfig, cfgPath)
+	os.Setenv(config.EnvEndpoint, space.LocalEndpointPrefix+filepath.Join(dir, "store"))
+	os.Setenv(config.EnvEnvironments, "dev="+devBucket+",live=live.bucket")
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestListBucket(t *testing.T) {
 	argv := []string{
 		"cli", "list-internal",
@@ -19,27 +50,22 @@ func TestListBucket(t *testing.T) {
 		t.Errorf("case 1 got error %v", err)
 	}
 
-	spaceKey := service.SpaceKey
-	service.SpaceKey = "test"
+	argv = []string{
+		"cli", "--endpoint", "https://foo.bar.com", "list-internal",
+	}
 
 	err = cli.Run(argv)
-	service.SpaceKey = spaceKey
 	if err == nil {
 		t.Error("case 2 got no error, want error")
 	}
 }
 
 func TestListObjects(t *testing.T) {
-	devBucket, err := service.GetBucket("dev")
-	if err != nil {
-		t.Error(err)
-	}
-
 	argv := []string{
 		"cli", "list-internal", devBucket,
 	}
 
-	err = cli.Run(argv)
+	err := cli.Run(argv)
 	if err != nil {
 		t.Errorf("case 1 got error %v", err)
 	}
@@ -110,6 +136,23 @@ func setupDownload(t *testing.T) {
 	}
 }
 
+func setupDownloadFolder(t *testing.T) {
+	argv := []string{
+		"cli", "pull", "-r",
+		"-o", "./tmp/folder",
+		"test/cli",
+	}
+	err := cli.Run(argv)
+	if err != nil {
+		t.Errorf("download folder setup got error %v", err)
+	}
+	for _, name := range []string{"cli.go", "main/main.go"} {
+		if _, err = os.Stat(filepath.Join("./tmp/folder", name)); err != nil {
+			t.Errorf("download folder got error %v", err)
+		}
+	}
+}
+
 func teardownDownload(t *testing.T) {
 	err := os.RemoveAll("./tmp")
 	if err != nil {
@@ -120,6 +163,458 @@ func teardownDownload(t *testing.T) {
 func TestPushAndDownloadAndRemoveFolder(t *testing.T) {
 	setupPushFolder(t)
 	setupDownload(t)
+	setupDownloadFolder(t)
 	teardownDownload(t)
 	teardownPushFolder(t)
 }
+
+// captureStdout of a command run with `argv`.
+func captureStdout(t *testing.T, argv []string) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	err = cli.Run(argv)
+	os.Stdout = stdout
+	w.Close()
+
+	out, _ := ioutil.ReadAll(r)
+	return string(out), err
+}
+
+func TestOutput(t *testing.T) {
+	if _, err := captureStdout(t, []string{"cli", "push", "-p", "output", "cli.go"}); err != nil {
+		t.Fatalf("setup got error %v", err)
+	}
+	defer cli.Run([]string{"cli", "remove", "output/cli.go"})
+
+	out, err := captureStdout(t, []string{"cli", "--output", "json", "list", "output/"})
+	objects := []map[string]interface{}{}
+	if err != nil || json.Unmarshal([]byte(out), &objects) != nil {
+		t.Fatalf("case 1 got %q, %v, want JSON", out, err)
+	}
+	if len(objects) != 1 || objects[0]["object"] != "output/cli.go" || objects[0]["last_modified"] == nil {
+		t.Errorf("case 1 got %v, want output/cli.go", objects)
+	}
+
+	out, err = captureStdout(t, []string{"cli", "--output", "csv", "list", "output/"})
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); err != nil || len(lines) != 2 || lines[0] != "Object,Size,Last modified" {
+		t.Errorf("case 2 got %q, %v, want header and 1 row", out, err)
+	}
+
+	out, err = captureStdout(t, []string{"cli", "--output", "plain", "push", "-p", "output", "cli.go"})
+	if err != nil || strings.TrimSpace(out) != "cli.go output/cli.go" {
+		t.Errorf("case 3 got %q, %v, want file and object name", out, err)
+	}
+
+	if _, err = captureStdout(t, []string{"cli", "--output", "xml", "list"}); err == nil {
+		t.Error("case 4 got no error, want invalid output format")
+	}
+
+	if _, err = captureStdout(t, []string{"cli", "push", "-p", "output", "cli_test.go"}); err != nil {
+		t.Fatalf("setup got error %v", err)
+	}
+	defer cli.Run([]string{"cli", "remove", "output/cli_test.go"})
+	out, err = captureStdout(t, []string{"cli", "--output", "plain", "list", "--limit", "1", "output/"})
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); err != nil || len(lines) != 1 || !strings.HasPrefix(lines[0], "output/cli.go ") {
+		t.Errorf("case 6 got %q, %v, want only output/cli.go", out, err)
+	}
+	out, err = captureStdout(t, []string{"cli", "--output", "plain", "list", "--start-after", "output/cli.go", "output/"})
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); err != nil || len(lines) != 1 || !strings.HasPrefix(lines[0], "output/cli_test.go ") {
+		t.Errorf("case 7 got %q, %v, want only output/cli_test.go", out, err)
+	}
+
+	out, err = captureStdout(t, []string{"cli", "--output", "plain", "list", "--glob", "*_test.go", "--newer-than", "1h", "output/"})
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); err != nil || len(lines) != 1 || !strings.HasPrefix(lines[0], "output/cli_test.go ") {
+		t.Errorf("case 8 got %q, %v, want only output/cli_test.go", out, err)
+	}
+	out, err = captureStdout(t, []string{"cli", "--output", "plain", "list", "--larger-than", "1GiB", "output/"})
+	if err != nil || out != "" {
+		t.Errorf("case 9 got %q, %v, want nothing", out, err)
+	}
+	if _, err = captureStdout(t, []string{"cli", "list", "--older-than", "soon", "output/"}); err == nil {
+		t.Error("case 10 got no error, want invalid age")
+	}
+
+	out, err = captureStdout(t, []string{"cli", "--output", "json", "du", "--sort", "size", "output"})
+	usage := []map[string]interface{}{}
+	if err != nil || json.Unmarshal([]byte(out), &usage) != nil || len(usage) != 1 || usage[0]["objects"] != 2.0 {
+		t.Errorf("case 11 got %q, %v, want 2 objects under output/", out, err)
+	}
+
+	defer os.RemoveAll("./tmp")
+	out, err = captureStdout(t, []string{"cli", "--output", "jsonl", "pull", "-o", "./tmp/output.go", "output/cli.go"})
+	object := map[string]interface{}{}
+	if err != nil || json.Unmarshal([]byte(out), &object) != nil || object["file"] != "./tmp/output.go" {
+		t.Errorf("case 5 got %q, %v, want JSON line with downloaded file", out, err)
+	}
+}
+
+// withStdin runs `fn` with `input` as stdin.
+func withStdin(t *testing.T, input string, fn func()) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(input)
+	w.Close()
+
+	stdin := os.Stdin
+	os.Stdin = r
+	fn()
+	os.Stdin = stdin
+	r.Close()
+}
+
+func TestRemoveRecursive(t *testing.T) {
+	for _, env := range []string{"dev", "live"} {
+		if _, err := captureStdout(t, []string{"cli", "push", "-r", "--env", env, "--prefix", "rm", "main"}); err != nil {
+			t.Fatalf("setup got error %v", err)
+		}
+	}
+	count := func(env string) int {
+		out, _ := captureStdout(t, []string{"cli", "--output", "plain", "list", "--env", env, "rm/"})
+		return len(strings.Fields(out)) / 3
+	}
+
+	out, err := captureStdout(t, []string{"cli", "--output", "plain", "rm", "-r", "--dry-run", "rm/"})
+	if err != nil || strings.TrimSpace(out) != "rm/main.go planned" || count("dev") != 1 {
+		t.Errorf("case 1 got %q, %v, want rm/main.go listed but kept", out, err)
+	}
+
+	withStdin(t, "n\n", func() {
+		_, err = captureStdout(t, []string{"cli", "rm", "-r", "rm/"})
+	})
+	if err == nil || count("dev") != 1 {
+		t.Errorf("case 2 got %v, want aborted removal", err)
+	}
+
+	withStdin(t, "", func() {
+		_, err = captureStdout(t, []string{"cli", "rm", "-r", "--yes", "--glob", "*.txt", "rm/"})
+	})
+	if err != nil || count("dev") != 1 {
+		t.Errorf("case 3 got %v, want nothing to remove", err)
+	}
+
+	if _, err = captureStdout(t, []string{"cli", "push", "-r", "--prefix", "rm2", "main"}); err != nil {
+		t.Fatalf("setup got error %v", err)
+	}
+	withStdin(t, "", func() {
+		_, err = captureStdout(t, []string{"cli", "rm", "-r", "--yes", "rm"})
+	})
+	out, _ = captureStdout(t, []string{"cli", "--output", "plain", "list", "rm2/"})
+	if err != nil || count("dev") != 0 || !strings.Contains(out, "rm2/main.go") {
+		t.Errorf("case 4 got %v, %q, want removal of rm/ only without confirmation", err, out)
+	}
+
+	withStdin(t, "y\n", func() {
+		_, err = captureStdout(t, []string{"cli", "rm", "-r", "--yes", "--env", "live", "rm/"})
+	})
+	if err == nil || count("live") != 1 {
+		t.Errorf("case 5 got %v, want live removal to be confirmed", err)
+	}
+
+	withStdin(t, "live\n", func() {
+		_, err = captureStdout(t, []string{"cli", "rm", "-r", "--env", "live", "rm/"})
+	})
+	if err != nil || count("live") != 0 {
+		t.Errorf("case 6 got %v, want live removal once confirmed", err)
+	}
+}
+
+func TestRemoveProtected(t *testing.T) {
+	if _, err := captureStdout(t, []string{"cli", "push", "-r", "--env", "live", "--prefix", "protected", "main"}); err != nil {
+		t.Fatalf("setup got error %v", err)
+	}
+	count := func() int {
+		out, _ := captureStdout(t, []string{"cli", "--output", "plain", "list", "--env", "live", "protected/"})
+		return len(strings.Fields(out)) / 3
+	}
+
+	var err error
+	withStdin(t, "y\n", func() {
+		_, err = captureStdout(t, []string{"cli", "rm", "--env", "live", "protected/main.go"})
+	})
+	if err == nil || count() != 1 {
+		t.Errorf("case 1 got %v, want live removal to be confirmed", err)
+	}
+
+	folder, err := ioutil.TempDir("", "space-cli-sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	withStdin(t, "n\n", func() {
+		_, err = captureStdout(t, []string{"cli", "sync", "--delete", "--env", "live", folder, "protected"})
+	})
+	if err == nil || count() != 1 {
+		t.Errorf("case 2 got %v, want live sync removal to be confirmed", err)
+	}
+
+	withStdin(t, "live\n", func() {
+		_, err = captureStdout(t, []string{"cli", "sync", "--delete", "--env", "live", folder, "protected"})
+	})
+	if err != nil || count() != 0 {
+		t.Errorf("case 3 got %v, want live sync removal once confirmed", err)
+	}
+}
+
+func TestPromote(t *testing.T) {
+	for _, prefix := range []string{"promote", "promote2"} {
+		if _, err := captureStdout(t, []string{"cli", "push", "-r", "--prefix", prefix, "main"}); err != nil {
+			t.Fatalf("setup got error %v", err)
+		}
+	}
+	count := func(env string) int {
+		out, _ := captureStdout(t, []string{"cli", "--output", "plain", "list", "--env", env, "promote/"})
+		return len(strings.Fields(out)) / 3
+	}
+
+	out, err := captureStdout(t, []string{"cli", "--output", "plain", "promote", "--to", "live", "--dry-run", "promote"})
+	if err != nil || strings.TrimSpace(out) != devBucket+"/promote/main.go promote/main.go" || count("live") != 0 {
+		t.Errorf("case 1 got %q, %v, want promote/main.go listed but not promoted", out, err)
+	}
+
+	withStdin(t, "y\n", func() {
+		_, err = captureStdout(t, []string{"cli", "promote", "--to", "live", "--yes", "promote/"})
+	})
+	if err == nil || count("live") != 0 {
+		t.Errorf("case 2 got %v, want live promotion to be confirmed", err)
+	}
+
+	withStdin(t, "live\n", func() {
+		_, err = captureStdout(t, []string{"cli", "promote", "--to", "live", "--verify", "promote/"})
+	})
+	if err != nil || count("live") != 1 {
+		t.Errorf("case 3 got %v, want promotion once confirmed", err)
+	}
+
+	if _, err = captureStdout(t, []string{"cli", "promote", "--to", "prod", "promote/"}); cli.ExitCode(err) != cli.ExitInvalidEnv {
+		t.Errorf("case 4 got %v, want invalid environment", err)
+	}
+}
+
+func TestCopyAndMove(t *testing.T) {
+	if _, err := captureStdout(t, []string{"cli", "push", "-r", "--prefix", "cp", "main"}); err != nil {
+		t.Fatalf("setup got error %v", err)
+	}
+	list := func(env, prefix string) string {
+		out, _ := captureStdout(t, []string{"cli", "--output", "plain", "list", "--env", env, prefix})
+		names := []string{}
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			if fields := strings.Fields(line); len(fields) > 0 {
+				names = append(names, fields[0])
+			}
+		}
+		return strings.Join(names, " ")
+	}
+
+	out, err := captureStdout(t, []string{"cli", "--output", "plain", "cp", "cp/main.go", "cp/sub/"})
+	if err != nil || strings.TrimSpace(out) != devBucket+"/cp/main.go "+devBucket+"/cp/sub/main.go" || list("dev", "cp/") != "cp/main.go cp/sub/main.go" {
+		t.Errorf("case 1 got %q, %v, want main.go copied into cp/sub/", out, err)
+	}
+
+	if _, err = captureStdout(t, []string{"cli", "mv", "-r", "cp/sub", "mv"}); err != nil || list("dev", "cp/sub/") != "" || list("dev", "mv/") != "mv/main.go" {
+		t.Errorf("case 2 got %v, want cp/sub moved to mv", err)
+	}
+
+	withStdin(t, "n\n", func() {
+		_, err = captureStdout(t, []string{"cli", "cp", "--to-env", "live", "mv/main.go", "cp/main.go"})
+	})
+	if err == nil || list("live", "cp/") != "" {
+		t.Errorf("case 3 got %v, want copy to live to be confirmed", err)
+	}
+
+	withStdin(t, "live\n", func() {
+		_, err = captureStdout(t, []string{"cli", "cp", "-r", "--to-env", "live", "--verify", "mv", "cp"})
+	})
+	if err != nil || list("live", "cp/") != "cp/main.go" {
+		t.Errorf("case 4 got %v, want mv copied to live", err)
+	}
+
+	withStdin(t, "y\n", func() {
+		_, err = captureStdout(t, []string{"cli", "cp", "--to-bucket", "live.bucket", "mv/main.go", "bucket/main.go"})
+	})
+	if err == nil || list("live", "bucket/") != "" {
+		t.Errorf("case 5 got %v, want copy to live bucket to be confirmed", err)
+	}
+	withStdin(t, "n\n", func() {
+		_, err = captureStdout(t, []string{"cli", "mv", "--bucket", "live.bucket", "cp/main.go", "bucket/main.go"})
+	})
+	if err == nil || list("live", "cp/") != "cp/main.go" {
+		t.Errorf("case 6 got %v, want move from live bucket to be confirmed", err)
+	}
+
+	if _, err = captureStdout(t, []string{"cli", "cp", "--to-bucket", "missing.bucket", "mv/main.go", "main.go"}); cli.ExitCode(err) != cli.ExitBucketNotFound {
+		t.Errorf("case 7 got %v, want bucket not found", err)
+	}
+	if _, err = captureStdout(t, []string{"cli", "mv", "cp/missing.go", "cp/main.go"}); cli.ExitCode(err) != cli.ExitNotFound {
+		t.Errorf("case 8 got %v, want not found", err)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "space-cli-env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := filepath.Join(dir, "config.json")
+	run := func(args ...string) (string, error) {
+		return captureStdout(t, append([]string{"cli", "--config", cfg}, args...))
+	}
+
+	if _, err = run("env", "add", "--protected", "staging", "staging.bucket"); err != nil {
+		t.Fatalf("case 1 got error %v", err)
+	}
+	out, err := run("--output", "json", "env", "show", "staging")
+	envs := []map[string]interface{}{}
+	if err != nil || json.Unmarshal([]byte(out), &envs) != nil || len(envs) != 1 || envs[0]["bucket"] != "staging.bucket" || envs[0]["protected"] != true {
+		t.Errorf("case 1 got %q, %v, want protected staging.bucket", out, err)
+	}
+	out, err = run("--output", "plain", "env", "list")
+	if err != nil || strings.Count(out, "\n") != 3 || !strings.Contains(out, "live live.bucket true") {
+		t.Errorf("case 2 got %q, %v, want dev, live and staging", out, err)
+	}
+
+	if _, err = run("push", "--env", "staging", "-p", "env", "cli.go"); err != nil {
+		t.Fatalf("case 3 got error %v", err)
+	}
+	withStdin(t, "y\n", func() {
+		_, err = run("rm", "-r", "--yes", "--env", "staging", "env/")
+	})
+	if err == nil {
+		t.Error("case 3 got no error, want protected staging to be confirmed")
+	}
+	if _, err = run("env", "add", "--protected=false", "staging", "staging.bucket"); err != nil {
+		t.Fatalf("case 4 got error %v", err)
+	}
+	if _, err = run("rm", "-r", "--yes", "--env", "staging", "env/"); err != nil {
+		t.Errorf("case 4 got %v, want removal without confirmation", err)
+	}
+
+	if _, err = run("env", "remove", "staging"); err != nil {
+		t.Errorf("case 5 got error %v", err)
+	}
+	if _, err = run("list", "--env", "staging"); cli.ExitCode(err) != cli.ExitInvalidEnv {
+		t.Errorf("case 5 got %v, want invalid environment", err)
+	}
+	if _, err = run("env", "remove", "live"); cli.ExitCode(err) != cli.ExitInvalidEnv {
+		t.Errorf("case 6 got %v, want live kept as it isn't in config file", err)
+	}
+}
+
+func TestShare(t *testing.T) {
+	dir, err := ioutil.TempDir("", "space-cli-share")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := filepath.Join(dir, "config.json")
+	ioutil.WriteFile(cfg, []byte("{}"), 0600)
+	run := func(args ...string) (string, error) {
+		return captureStdout(t, append([]string{"cli", "--config", cfg, "--endpoint", "localhost:9000", "--insecure",
+			"--region", "us-east-1", "--key", "key", "--secret", "secret"}, args...))
+	}
+
+	out, err := run("share", "build/app.tar.gz")
+	if err != nil || !strings.HasPrefix(out, "http://localhost:9000/"+devBucket+"/build/app.tar.gz?") || !strings.Contains(out, "X-Amz-Expires=86400") {
+		t.Errorf("case 1 got %q, %v, want download URL expiring in 24h", out, err)
+	}
+
+	out, err = run("--output", "json", "share", "--upload", "--expires", "72h", "build/app.tar.gz")
+	urls := []map[string]interface{}{}
+	if err != nil || json.Unmarshal([]byte(out), &urls) != nil || len(urls) != 1 || urls[0]["method"] != "PUT" || urls[0]["expires"] == nil {
+		t.Errorf("case 2 got %q, %v, want upload URL with expiry", out, err)
+	}
+
+	if _, err = run("share", "--env", "live", "--expires", "72h", "build/app.tar.gz"); err == nil {
+		t.Error("case 3 got no error, want live limited to 24h")
+	}
+	if _, err = run("env", "add", "--max-share-expiry", "1h", "dev", devBucket); err != nil {
+		t.Fatalf("case 4 got error %v", err)
+	}
+	if _, err = run("share", "--expires", "2h", "build/app.tar.gz"); err == nil {
+		t.Error("case 4 got no error, want dev limited to 1h")
+	}
+	if _, err = run("env", "add", "--max-share-expiry", "30d", "dev", devBucket); cli.ExitCode(err) != cli.ExitUsage {
+		t.Errorf("case 5 got %v, want invalid max share expiry", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, cli.ExitOK},
+		{fmt.Errorf("failed"), cli.ExitFailure},
+		{&space.BatchError{Op: "remove", Total: 2, Failed: []space.ObjectError{{ObjectName: "a.txt"}}}, cli.ExitPartialFailure},
+		{fmt.Errorf("wrapped: %w", &space.TransferError{}), cli.ExitPartialFailure},
+		{&space.Error{Kind: space.ErrAccessDenied, Err: fmt.Errorf("denied")}, cli.ExitAccessDenied},
+		{fmt.Errorf("interrupted: %w", context.Canceled), cli.ExitInterrupted},
+	}
+	for i, c := range cases {
+		if got := cli.ExitCode(c.err); got != c.want {
+			t.Errorf("case %v got %v, want %v", i+1, got, c.want)
+		}
+	}
+
+	runs := []struct {
+		argv []string
+		want int
+	}{
+		{[]string{"cli", "pull", "-o", "./tmp/missing.txt", "missing.txt"}, cli.ExitNotFound},
+		{[]string{"cli", "list", "--env", "staging"}, cli.ExitInvalidEnv},
+		{[]string{"cli", "pull"}, cli.ExitUsage},
+		{[]string{"cli", "--retries", "-1", "list"}, cli.ExitUsage},
+		{[]string{"cli", "du", "--depth", "-1"}, cli.ExitUsage},
+		{[]string{"cli", "sync", "--delete", "."}, cli.ExitUsage},
+		{[]string{"cli", "--timeout", "1ns", "list"}, cli.ExitTimeout},
+	}
+	defer os.RemoveAll("./tmp")
+	for i, c := range runs {
+		if got := cli.ExitCode(cli.Run(c.argv)); got != c.want {
+			t.Errorf("case %v got %v, want %v", len(cases)+i+1, got, c.want)
+		}
+	}
+
+	fp := filepath.Join("./tmp", "big.bin")
+	os.MkdirAll("./tmp", 0755)
+	ioutil.WriteFile(fp, []byte("test content"), 0644)
+	s := space.NewFromBackend(cancelledBackend{space.NewMemoryBackend(devBucket)}).
+		WithEnvironments(map[string]string{"dev": devBucket}).
+		WithRetry(space.NoRetry)
+	_, err := s.UploadBigFile(context.Background(), fp, "dev", "test")
+	if got := cli.ExitCode(err); got != cli.ExitInterrupted {
+		t.Errorf("case %v got %v, %v, want %v", len(cases)+len(runs)+1, got, err, cli.ExitInterrupted)
+	}
+}
+
+// cancelledBackend fails every upload of a part as if interrupted.
+type cancelledBackend struct {
+	space.Backend
+}
+
+func (b cancelledBackend) PutObjectPart(ctx context.Context, bucketName, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (space.ObjectPart, error) {
+	return space.ObjectPart{}, context.Canceled
+}
+
+func TestRetries(t *testing.T) {
+	attempts := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	argv := []string{
+		"cli", "--endpoint", strings.TrimPrefix(server.URL, "http://"), "--insecure", "--region", "us-east-1",
+		"--key", "key", "--secret", "secret", "--retries", "0", "list",
+	}
+	if err := cli.Run(argv); err == nil || atomic.LoadInt32(&attempts) != 1 {
+		t.Errorf("case 1 got %v attempts, %v, want 1 attempt and error", atomic.LoadInt32(&attempts), err)
+	}
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/lebenasa/space"
+	"github.com/lebenasa/space/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+func copyAction(c *cli.Context) error {
+	return copyObjects(c, false)
+}
+
+func moveAction(c *cli.Context) error {
+	return copyObjects(c, true)
+}
+
+// copyObjects from `--env` (or `--bucket`) to `--to-env` (or `--to-bucket`), removing sources if `move`.
+// Writing to a protected environment, or moving from it, has to be confirmed.
+func copyObjects(c *cli.Context, move bool) error {
+	src, dst := c.Args().Get(0), c.Args().Get(1)
+	if src == "" || dst == "" {
+		return cli.Exit("No source or destination given.", ExitUsage)
+	}
+
+	profile, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	env, toEnv := c.String("env"), c.String("to-env")
+	if toEnv == "" {
+		toEnv = env
+	}
+	srcBucket, env, err := bucketOf(profile, env, c.String("bucket"))
+	if err != nil {
+		return err
+	}
+	dstBucket, toEnv, err := bucketOf(profile, toEnv, c.String("to-bucket"))
+	if err != nil {
+		return err
+	}
+
+	s, err := newSpace(c)
+	if err != nil {
+		return err
+	}
+	s = s.WithJobs(c.Int("jobs")).WithVerify(c.Bool("verify"))
+	if s, err = withFilters(c, s); err != nil {
+		return err
+	}
+
+	r, err := newRenderer(c, "Source", "Destination", "Error")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Context
+	op, done := "Copy", "copied"
+	if move {
+		op, done = "Move", "moved"
+	}
+	if !c.Bool("recursive") && strings.HasSuffix(dst, "/") {
+		dst += path.Base(src)
+	}
+	if c.Bool("dry-run") {
+		if !c.Bool("recursive") {
+			r.Append(srcBucket+"/"+src, dstBucket+"/"+dst, nil)
+			return r.Render()
+		}
+		srcPrefix := src
+		if !strings.HasSuffix(srcPrefix, "/") {
+			srcPrefix += "/"
+		}
+		err = s.WalkObjects(ctx, srcBucket, space.ListOptions{Prefix: srcPrefix, Recursive: true}, func(object space.ObjectInfo) error {
+			r.Append(srcBucket+"/"+object.Key, path.Join(dstBucket, dst, strings.TrimPrefix(object.Key, srcPrefix)), nil)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return r.Render()
+	}
+
+	prompt := fmt.Sprintf("%v %v/%v to %v/%v?", op, srcBucket, src, dstBucket, dst)
+	if profile.Protected(toEnv) && !confirm(prompt, toEnv, true, false) {
+		return fmt.Errorf("Aborted, nothing %v", done)
+	}
+	if move && profile.Protected(env) && !confirm(prompt, env, true, false) {
+		return fmt.Errorf("Aborted, nothing %v", done)
+	}
+
+	if !c.Bool("recursive") {
+		if move {
+			err = s.Move(ctx, srcBucket, src, dstBucket, dst)
+		} else {
+			err = s.Copy(ctx, srcBucket, src, dstBucket, dst, nil)
+		}
+		if err != nil {
+			return err
+		}
+		r.Append(srcBucket+"/"+src, dstBucket+"/"+dst, nil)
+		return r.Render()
+	}
+
+	s = s.WithReport(func(result space.TransferResult) {
+		r.Append(result.Path, dstBucket+"/"+result.ObjectName, result.Err)
+	})
+	if move {
+		_, err = s.MovePrefix(ctx, srcBucket, src, dstBucket, dst)
+	} else {
+		_, err = s.CopyPrefix(ctx, srcBucket, src, dstBucket, dst)
+	}
+	if rErr := r.Render(); err == nil {
+		err = rErr
+	}
+	return err
+}
+
+// bucketOf an environment unless `bucket` is given, in which case the environment is the one using that bucket, if any.
+// Environments not in selected profile are invalid.
+func bucketOf(profile config.Profile, env, bucket string) (string, string, error) {
+	if bucket != "" {
+		for _, name := range profile.EnvNames() {
+			if profile.Environments[name] == bucket {
+				return bucket, name, nil
+			}
+		}
+		return bucket, "", nil
+	}
+	bucket, err := profile.Bucket(env)
+	if err != nil {
+		return "", "", invalidEnv(err)
+	}
+	return bucket, env, nil
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/urfave/cli/v2"
+)
+
+func duAction(c *cli.Context) error {
+	if c.Int("depth") < 0 {
+		return cli.Exit(fmt.Sprintf("Invalid depth %v, want 0 or more", c.Int("depth")), ExitUsage)
+	}
+	env, err := handleEnvFlag(c)
+	if err != nil {
+		return err
+	}
+	sortBy, err := handleEnum(c.String("sort"), []string{"prefix", "size", "objects"})
+	if err != nil {
+		return err
+	}
+
+	s, err := newSpace(c)
+	if err != nil {
+		return err
+	}
+	if s, err = withFilters(c, s); err != nil {
+		return err
+	}
+
+	r, err := newRenderer(c, "Prefix", "Objects", "Size")
+	if err != nil {
+		return err
+	}
+
+	usage, err := s.Usage(c.Context, env, c.Args().First(), c.Int("depth"))
+	if err != nil {
+		return err
+	}
+
+	switch sortBy {
+	case "size":
+		sort.SliceStable(usage, func(i, j int) bool {
+			return usage[i].Size > usage[j].Size
+		})
+	case "objects":
+		sort.SliceStable(usage, func(i, j int) bool {
+			return usage[i].Objects > usage[j].Objects
+		})
+	}
+
+	for _, u := range usage {
+		if c.Bool("human") {
+			r.Append(u.Prefix, u.Objects, formatSize(u.Size))
+			continue
+		}
+		r.Append(u.Prefix, u.Objects, u.Size)
+	}
+	return r.Render()
+}
+
+// formatSize in binary units, e.g. 1.5 GiB.
+func formatSize(size int64) string {
+	const unit = 1 << 10
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTP"[exp])
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lebenasa/space/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+func envListAction(c *cli.Context) error {
+	profile, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	r, err := newRenderer(c, "Environment", "Bucket", "Protected", "Max share expiry")
+	if err != nil {
+		return err
+	}
+	for _, env := range profile.EnvNames() {
+		r.Append(env, profile.Environments[env], profile.Protected(env), shareExpiry(profile, env))
+	}
+	return r.Render()
+}
+
+func envShowAction(c *cli.Context) error {
+	env := c.Args().First()
+	if env == "" {
+		return cli.Exit("No environment given.", ExitUsage)
+	}
+	profile, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	bucket, err := profile.Bucket(env)
+	if err != nil {
+		return invalidEnv(err)
+	}
+
+	r, err := newRenderer(c, "Environment", "Bucket", "Protected", "Max share expiry")
+	if err != nil {
+		return err
+	}
+	r.Append(env, bucket, profile.Protected(env), shareExpiry(profile, env))
+	return r.Render()
+}
+
+func envAddAction(c *cli.Context) error {
+	env, bucket := c.Args().Get(0), c.Args().Get(1)
+	if env == "" || bucket == "" {
+		return cli.Exit("No environment or bucket given.", ExitUsage)
+	}
+
+	expiry := c.String("max-share-expiry")
+	if expiry != "" {
+		if d, err := time.ParseDuration(expiry); err != nil || d <= 0 || d > config.MaxShareExpiry {
+			return cli.Exit(fmt.Sprintf("Invalid max share expiry %v, want a duration like 24h up to %v", expiry, config.MaxShareExpiry), ExitUsage)
+		}
+	}
+
+	return config.Edit(c.String("config"), c.String("profile"), func(p *config.Profile) error {
+		if p.Environments == nil {
+			p.Environments = map[string]string{}
+		}
+		p.Environments[env] = bucket
+
+		if c.IsSet("protected") || expiry != "" {
+			if p.EnvSettings == nil {
+				p.EnvSettings = map[string]config.EnvSettings{}
+			}
+			settings := p.EnvSettings[env]
+			if c.IsSet("protected") {
+				settings.Protected = c.Bool("protected")
+			}
+			if expiry != "" {
+				settings.MaxShareExpiry = expiry
+			}
+			p.EnvSettings[env] = settings
+		}
+		fmt.Fprintf(os.Stderr, "Environment %v uses bucket %v\n", env, bucket)
+		return nil
+	})
+}
+
+func envRemoveAction(c *cli.Context) error {
+	env := c.Args().First()
+	if env == "" {
+		return cli.Exit("No environment given.", ExitUsage)
+	}
+
+	return config.Edit(c.String("config"), c.String("profile"), func(p *config.Profile) error {
+		if _, err := p.Bucket(env); err != nil {
+			return invalidEnv(fmt.Errorf("Environment %v isn't in config file, possible values: %v", env, p.EnvNames()))
+		}
+		delete(p.Environments, env)
+		delete(p.EnvSettings, env)
+		fmt.Fprintf(os.Stderr, "Environment %v removed\n", env)
+		return nil
+	})
+}
+
+// shareExpiry of an environment as shown by `env` commands, or the error of an invalid one.
+func shareExpiry(profile config.Profile, env string) interface{} {
+	expiry, err := profile.ShareExpiry(env)
+	if err != nil {
+		return err
+	}
+	return expiry
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lebenasa/space"
+
+	"github.com/urfave/cli/v2"
+)
+
+// filterFlags shared by commands selecting objects, see `parseFilters`.
+func filterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "glob",
+			Usage: "Only objects matching pattern, e.g. '*.tar.gz' (matched against full name if it has '/')",
+		},
+		&cli.StringFlag{
+			Name:  "larger-than",
+			Usage: "Only objects bigger than size, e.g. 100MB or 1GiB",
+		},
+		&cli.StringFlag{
+			Name:  "smaller-than",
+			Usage: "Only objects smaller than size, e.g. 100MB or 1GiB",
+		},
+		&cli.StringFlag{
+			Name:  "older-than",
+			Usage: "Only objects last modified before given age, e.g. 30d, 2w or 12h",
+		},
+		&cli.StringFlag{
+			Name:  "newer-than",
+			Usage: "Only objects last modified within given age, e.g. 30d, 2w or 12h",
+		},
+		&cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "Only objects having tag key=value, can be repeated",
+		},
+	}
+}
+
+// withFilters from command flags applied to `s`.
+func withFilters(c *cli.Context, s space.Space) (space.Space, error) {
+	filters, err := parseFilters(c)
+	if err != nil {
+		return s, err
+	}
+	return s.WithFilter(filters...), nil
+}
+
+// parseFilters from `filterFlags`, all of them must match.
+func parseFilters(c *cli.Context) (filters []space.Filter, err error) {
+	if pattern := c.String("glob"); pattern != "" {
+		if _, err = path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Invalid glob '%v': %v", pattern, err)
+		}
+		filters = append(filters, space.Glob(pattern))
+	}
+
+	sizes := []struct {
+		flag   string
+		filter func(int64) space.Filter
+	}{
+		{"larger-than", space.LargerThan},
+		{"smaller-than", space.SmallerThan},
+	}
+	for _, size := range sizes {
+		if text := c.String(size.flag); text != "" {
+			bytes, err := parseSize(text)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, size.filter(bytes))
+		}
+	}
+
+	ages := []struct {
+		flag   string
+		filter func(time.Duration) space.Filter
+	}{
+		{"older-than", space.OlderThan},
+		{"newer-than", space.NewerThan},
+	}
+	for _, age := range ages {
+		if text := c.String(age.flag); text != "" {
+			d, err := parseAge(text)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, age.filter(d))
+		}
+	}
+
+	for _, tag := range c.StringSlice("tag") {
+		keyval := strings.SplitN(tag, "=", 2)
+		if len(keyval) != 2 || keyval[0] == "" {
+			return nil, fmt.Errorf("Invalid tag filter '%v', want key=value", tag)
+		}
+		filters = append(filters, space.HasTag(keyval[0], keyval[1]))
+	}
+	return filters, nil
+}
+
+// sizeUnits accepted by `parseSize`, longest suffix first.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// parseSize like "100MB" (decimal), "1GiB" or "1G" (binary), or plain bytes.
+func parseSize(text string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(text))
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper, unit = strings.TrimSpace(strings.TrimSuffix(upper, u.suffix)), u.bytes
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(upper, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("Invalid size '%v', e.g. 100MB or 1GiB", text)
+	}
+	return int64(value * float64(unit)), nil
+}
+
+// parseAge like "30d" or "2w", otherwise any `time.ParseDuration` value like "12h".
+func parseAge(text string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if strings.HasSuffix(text, suffix) {
+			value, err := strconv.ParseFloat(strings.TrimSuffix(text, suffix), 64)
+			if err != nil || value < 0 {
+				return 0, fmt.Errorf("Invalid age '%v', e.g. 30d, 2w or 12h", text)
+			}
+			return time.Duration(value * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(text)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Invalid age '%v', e.g. 30d, 2w or 12h", text)
+	}
+	return d, nil
+}
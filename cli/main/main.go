@@ -10,6 +10,7 @@ import (
 func main() {
 	err := cli.Run(os.Args)
 	if err != nil {
-		log.Fatalln(err)
+		log.Println(err)
 	}
+	os.Exit(cli.ExitCode(err))
 }
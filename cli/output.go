@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/urfave/cli/v2"
+)
+
+// outputFormats accepted by global `--output` flag, the first one is the default.
+var outputFormats = []string{"table", "json", "jsonl", "csv", "tsv", "plain"}
+
+// outputFormat from global `--output` flag. Commands like `pull` have their own `--output` flag,
+// so it's looked up from the outermost context having it.
+func outputFormat(c *cli.Context) (string, error) {
+	lineage := c.Lineage()
+	for i := len(lineage) - 1; i >= 0; i-- {
+		if format := lineage[i].String("output"); format != "" {
+			return handleEnum(format, outputFormats)
+		}
+	}
+	return outputFormats[0], nil
+}
+
+// renderer writes command results as rows in selected output format.
+// Line-based formats are written as soon as a row is appended, table and json are written by `Render`.
+type renderer struct {
+	format string
+	out    io.Writer
+	header []string
+	rows   [][]interface{}
+	csv    *csv.Writer
+}
+
+func newRenderer(c *cli.Context, header ...string) (*renderer, error) {
+	format, err := outputFormat(c)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &renderer{format: format, out: os.Stdout, header: header}
+	if format == "csv" || format == "tsv" {
+		r.csv = csv.NewWriter(r.out)
+		if format == "tsv" {
+			r.csv.Comma = '\t'
+		}
+		r.csv.Write(header)
+	}
+	return r, nil
+}
+
+// Append a row with one value per header column.
+func (r *renderer) Append(row ...interface{}) {
+	switch r.format {
+	case "jsonl":
+		json.NewEncoder(r.out).Encode(r.object(row))
+	case "csv", "tsv":
+		r.csv.Write(r.strings(row))
+		r.csv.Flush()
+	case "plain":
+		fmt.Fprintln(r.out, strings.TrimRight(strings.Join(r.strings(row), " "), " "))
+	default:
+		r.rows = append(r.rows, row)
+	}
+}
+
+// Render buffered rows, call once every row is appended.
+func (r *renderer) Render() error {
+	switch r.format {
+	case "table":
+		t := table.NewWriter()
+		t.SetOutputMirror(r.out)
+		header := table.Row{}
+		for _, column := range r.header {
+			header = append(header, column)
+		}
+		t.AppendHeader(header)
+		for _, row := range r.rows {
+			values := table.Row{}
+			for _, value := range r.strings(row) {
+				values = append(values, value)
+			}
+			t.AppendRow(values)
+		}
+		t.SetStyle(table.StyleColoredBlueWhiteOnBlack)
+		t.Render()
+	case "json":
+		objects := make([]map[string]interface{}, len(r.rows))
+		for i, row := range r.rows {
+			objects[i] = r.object(row)
+		}
+		encoder := json.NewEncoder(r.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(objects)
+	case "csv", "tsv":
+		r.csv.Flush()
+		return r.csv.Error()
+	}
+	return nil
+}
+
+// object of a row keyed by snake_case header, e.g. "Last modified" becomes "last_modified".
+func (r *renderer) object(row []interface{}) map[string]interface{} {
+	object := map[string]interface{}{}
+	for i, column := range r.header {
+		key := strings.ReplaceAll(strings.ToLower(column), " ", "_")
+		if i >= len(row) {
+			object[key] = nil
+			continue
+		}
+		if err, ok := row[i].(error); ok {
+			object[key] = err.Error()
+			continue
+		}
+		object[key] = row[i]
+	}
+	return object
+}
+
+func (r *renderer) strings(row []interface{}) []string {
+	values := make([]string, len(row))
+	for i, value := range row {
+		switch v := value.(type) {
+		case nil:
+		case time.Time:
+			values[i] = v.Format(time.RFC3339)
+		default:
+			values[i] = fmt.Sprint(v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lebenasa/space"
+
+	"github.com/urfave/cli/v2"
+)
+
+func promoteAction(c *cli.Context) error {
+	prefix := c.Args().First()
+	if prefix == "" {
+		return cli.Exit("No Space prefix given.", ExitUsage)
+	}
+
+	profile, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	from, to := c.String("from"), c.String("to")
+	bucket, err := profile.Bucket(from)
+	if err != nil {
+		return invalidEnv(err)
+	}
+	if _, err = profile.Bucket(to); err != nil {
+		return invalidEnv(err)
+	}
+
+	s, err := newSpace(c)
+	if err != nil {
+		return err
+	}
+	s = s.WithJobs(c.Int("jobs")).WithVerify(c.Bool("verify"))
+	if s, err = withFilters(c, s); err != nil {
+		return err
+	}
+
+	r, err := newRenderer(c, "Source", "Object", "Error")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Context
+	objectNames, size := []string{}, int64(0)
+	err = s.Walk(ctx, from, space.ListOptions{Prefix: folderPrefix(prefix), Recursive: true}, func(object space.ObjectInfo) error {
+		objectNames = append(objectNames, object.Key)
+		size += object.Size
+		if c.Bool("dry-run") {
+			r.Append(bucket+"/"+object.Key, object.Key, nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	summary := fmt.Sprintf("%v objects (%v) from %v to %v", len(objectNames), formatSize(size), from, to)
+	if c.Bool("dry-run") {
+		fmt.Fprintf(os.Stderr, "Would promote %v\n", summary)
+		return r.Render()
+	}
+	if len(objectNames) == 0 {
+		fmt.Fprintln(os.Stderr, "Nothing to promote")
+		return r.Render()
+	}
+	if !confirm(fmt.Sprintf("Promote %v?", summary), to, profile.Protected(to), c.Bool("yes")) {
+		return fmt.Errorf("Aborted, nothing promoted")
+	}
+
+	s = s.WithReport(func(result space.TransferResult) {
+		r.Append(result.Path, result.ObjectName, result.Err)
+	})
+	_, err = s.WithFilter().PromoteObjects(ctx, from, to, objectNames)
+	if rErr := r.Render(); err == nil {
+		err = rErr
+	}
+	return err
+}
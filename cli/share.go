@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+)
+
+func shareAction(c *cli.Context) error {
+	objectName := c.Args().First()
+	if objectName == "" {
+		return cli.Exit("No Space object given.", ExitUsage)
+	}
+
+	env, err := handleEnvFlag(c)
+	if err != nil {
+		return err
+	}
+
+	s, err := newSpace(c)
+	if err != nil {
+		return err
+	}
+
+	shared, err := s.Share(c.Context, env, objectName, c.Duration("expires"), c.Bool("upload"))
+	if err != nil {
+		return err
+	}
+
+	r, err := newRenderer(c, "Object", "Method", "URL", "Expires")
+	if err != nil {
+		return err
+	}
+	// A bare URL is easier to copy than a table cell.
+	if r.format == "table" {
+		fmt.Println(shared.URL)
+		return nil
+	}
+	r.Append(objectName, shared.Method, shared.URL, shared.Expires)
+	return r.Render()
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/lebenasa/space"
+
+	"github.com/urfave/cli/v2"
+)
+
+func syncAction(c *cli.Context) error {
+	folder := c.Args().Get(0)
+	if folder == "" {
+		return fmt.Errorf("Invalid folder: '%v'", folder)
+	}
+	prefix := c.Args().Get(1)
+	if prefix == "" && c.Bool("delete") && !c.Bool("pull") {
+		return cli.Exit("No Space prefix given, --delete would remove every other object of the bucket.", ExitUsage)
+	}
+
+	env, err := handleEnvFlag(c)
+	if err != nil {
+		return err
+	}
+
+	s, err := newSpace(c)
+	if err != nil {
+		return err
+	}
+	s = s.WithJobs(c.Int("jobs")).
+		WithDelete(c.Bool("delete")).
+		WithIgnore(parseIgnore(c.StringSlice("exclude"), c.StringSlice("include")))
+	if s, err = withFilters(c, s); err != nil {
+		return err
+	}
+
+	ctx := c.Context
+
+	var actions []space.SyncAction
+	if c.Bool("pull") {
+		actions, err = s.PlanPull(ctx, prefix, folder, env)
+	} else {
+		actions, err = s.PlanPush(ctx, folder, env, prefix)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("dry-run") {
+		r, err := newRenderer(c, "Action", "File", "Object", "Reason")
+		if err != nil {
+			return err
+		}
+		for _, action := range actions {
+			r.Append(action.Op, action.Path, action.ObjectName, action.Reason)
+		}
+		return r.Render()
+	}
+
+	removals := 0
+	for _, action := range actions {
+		if action.Op == space.SyncRemoveObject {
+			removals++
+		}
+	}
+	profile, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	prompt := fmt.Sprintf("Sync %v to %v, removing %v objects?", folder, env, removals)
+	if removals > 0 && profile.Protected(env) && !confirm(prompt, env, true, false) {
+		return fmt.Errorf("Aborted, nothing synced")
+	}
+
+	r, err := newRenderer(c, "Action", "File", "Object", "Error")
+	if err != nil {
+		return err
+	}
+	s = s.WithReport(func(result space.TransferResult) {
+		r.Append(result.Op, result.Path, result.ObjectName, result.Err)
+	})
+
+	_, err = s.Sync(ctx, env, actions)
+	if rErr := r.Render(); err == nil {
+		err = rErr
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/lebenasa/space"
+
+	"github.com/urfave/cli/v2"
+)
+
+func treeAction(c *cli.Context) error {
+	env, err := handleEnvFlag(c)
+	if err != nil {
+		return err
+	}
+
+	s, err := newSpace(c)
+	if err != nil {
+		return err
+	}
+	if s, err = withFilters(c, s); err != nil {
+		return err
+	}
+
+	format, err := outputFormat(c)
+	if err != nil {
+		return err
+	}
+
+	root, err := s.Tree(c.Context, env, c.Args().First(), c.Int("max-depth"))
+	if err != nil {
+		return err
+	}
+
+	if format == "table" || format == "plain" {
+		fmt.Printf("%v %v\n", rootName(root), nodeTotals(root))
+		printTree(root, "")
+		fmt.Printf("\n%v directories, %v objects\n", countDirs(root), root.Objects)
+		return nil
+	}
+
+	r, err := newRenderer(c, "Key", "Depth", "Objects", "Size", "Last modified")
+	if err != nil {
+		return err
+	}
+	appendTree(r, root, 0)
+	return r.Render()
+}
+
+func rootName(root *space.TreeNode) string {
+	if root.Key == "" {
+		return "."
+	}
+	return root.Key
+}
+
+// nodeTotals like "(3 objects, 1.5 GiB)" for directories, or object's size.
+func nodeTotals(node *space.TreeNode) string {
+	if !node.IsDir() {
+		return fmt.Sprintf("(%v)", formatSize(node.Size))
+	}
+	return fmt.Sprintf("(%v objects, %v)", node.Objects, formatSize(node.Size))
+}
+
+// printTree of node's children like `tree`, with `indent` before each line.
+func printTree(node *space.TreeNode, indent string) {
+	for i, child := range node.Children {
+		branch, next := "├── ", "│   "
+		if i == len(node.Children)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Printf("%v%v%v %v\n", indent, branch, child.Name, nodeTotals(child))
+		printTree(child, indent+next)
+	}
+}
+
+func countDirs(node *space.TreeNode) (count int) {
+	for _, child := range node.Children {
+		if child.IsDir() {
+			count += 1 + countDirs(child)
+		}
+	}
+	return count
+}
+
+// appendTree as rows in depth-first order, for machine-readable output.
+func appendTree(r *renderer, node *space.TreeNode, depth int) {
+	r.Append(node.Key, depth, node.Objects, node.Size, node.LastModified)
+	for _, child := range node.Children {
+		appendTree(r, child, depth+1)
+	}
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/lebenasa/space"
+
+	"github.com/urfave/cli/v2"
+)
+
+func newUploadsSpace(c *cli.Context) (s space.Space, env string, err error) {
+	env, err = handleEnvFlag(c)
+	if err != nil {
+		return
+	}
+
+	s, err = newSpace(c)
+	if err != nil {
+		return
+	}
+
+	stateDir, err := space.DefaultStateDir()
+	if err != nil {
+		return
+	}
+	return s.WithStateDir(stateDir), env, nil
+}
+
+func uploadsListAction(c *cli.Context) error {
+	s, env, err := newUploadsSpace(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Context
+
+	uploads, err := s.IncompleteUploads(ctx, env, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	stateDir, _ := space.DefaultStateDir()
+	states, err := space.LoadUploadStates(stateDir)
+	if err != nil {
+		return err
+	}
+	resumable := map[string]string{}
+	for _, state := range states {
+		resumable[state.UploadID] = state.FilePath
+	}
+
+	r, err := newRenderer(c, "Object", "Upload ID", "Initiated", "Uploaded size", "Resumable from")
+	if err != nil {
+		return err
+	}
+	for _, upload := range uploads {
+		r.Append(upload.Key, upload.UploadID, upload.Initiated, upload.Size, resumable[upload.UploadID])
+	}
+	return r.Render()
+}
+
+func uploadsAbortAction(c *cli.Context) error {
+	objectName := c.Args().Get(0)
+	uploadID := c.Args().Get(1)
+	if objectName == "" && !c.Bool("all") {
+		return cli.Exit("No Space object given.", 2)
+	}
+
+	s, env, err := newUploadsSpace(c)
+	if err != nil {
+		return err
+	}
+
+	r, err := newRenderer(c, "Object", "Upload ID")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Context
+
+	uploads, err := s.IncompleteUploads(ctx, env, objectName)
+	if err != nil {
+		return err
+	}
+
+	aborted := 0
+	for _, upload := range uploads {
+		if !c.Bool("all") && upload.Key != objectName {
+			continue
+		}
+		if uploadID != "" && upload.UploadID != uploadID {
+			continue
+		}
+		if err = s.AbortUpload(ctx, env, upload.Key, upload.UploadID); err != nil {
+			return err
+		}
+		r.Append(upload.Key, upload.UploadID)
+		aborted++
+	}
+
+	if aborted == 0 {
+		return fmt.Errorf("No incomplete upload found for '%v'", objectName)
+	}
+	return r.Render()
+}
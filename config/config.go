@@ -0,0 +1,400 @@
+package config
+
+// Runtime configuration for Space clients.
+// Values are resolved in order: compiled-in `service` fallback, config file,
+// then `SPACE_*` environment variables. CLI flags are applied on top by the caller.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lebenasa/space/service"
+)
+
+// Environment variables read by `Load`.
+const (
+	EnvConfig       = "SPACE_CONFIG"
+	EnvProfile      = "SPACE_PROFILE"
+	EnvEndpoint     = "SPACE_ENDPOINT"
+	EnvKey          = "SPACE_KEY"
+	EnvSecret       = "SPACE_SECRET"
+	EnvEnvironments = "SPACE_ENVIRONMENTS"
+	EnvRegion       = "SPACE_REGION"
+	EnvCACert       = "SPACE_CA_CERT"
+	// EnvSessionToken is only read by "env" credential source, with `EnvKey` and `EnvSecret`.
+	EnvSessionToken = "SPACE_SESSION_TOKEN"
+	EnvHelper       = "SPACE_CREDENTIALS_HELPER"
+)
+
+// DefaultProfileName selects top-level profile of a config file.
+const DefaultProfileName = "default"
+
+// Profile to access a single endpoint with a single account.
+type Profile struct {
+	Endpoint string `json:"endpoint,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Secret   string `json:"secret,omitempty"`
+
+	// Credentials sources tried in order until one has a key, `CredentialSources` if empty.
+	// Key and secret above are the "static" source.
+	Credentials []string `json:"credentials,omitempty"`
+	// CredentialsFile is an AWS shared credentials file, "~/.aws/credentials" if empty,
+	// CredentialsProfile selects a profile in it, "default" if empty.
+	CredentialsFile    string `json:"credentials_file,omitempty"`
+	CredentialsProfile string `json:"credentials_profile,omitempty"`
+	// CredentialsHelper is a command printing credentials as JSON, see `space.HelperProvider`.
+	CredentialsHelper string `json:"credentials_helper,omitempty"`
+
+	// Insecure uses plain HTTP instead of HTTPS.
+	Insecure bool `json:"insecure,omitempty"`
+	// CACert is a PEM bundle trusted besides system roots, e.g. for a private CA.
+	CACert string `json:"ca_cert,omitempty"`
+	// ClientCert and ClientKey are PEM files authenticating the client over TLS.
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	// Addressing of buckets, one of `Addressings`: detected by default, or forced to path-style or virtual-host.
+	Addressing string `json:"addressing,omitempty"`
+	// Region of the endpoint, otherwise detected on first request.
+	Region string `json:"region,omitempty"`
+
+	// Environments maps environment name, e.g. "dev", to bucket name.
+	Environments map[string]string `json:"environments,omitempty"`
+	// EnvSettings of some environments, by name.
+	EnvSettings map[string]EnvSettings `json:"env_settings,omitempty"`
+}
+
+// EnvSettings of a single environment.
+type EnvSettings struct {
+	// Protected environments require confirmation typing their name before destructive commands.
+	Protected bool `json:"protected,omitempty"`
+	// MaxShareExpiry of presigned URLs to objects of the environment, e.g. "24h", at most `MaxShareExpiry`.
+	MaxShareExpiry string `json:"max_share_expiry,omitempty"`
+}
+
+// MaxShareExpiry of presigned URLs allowed by S3, used unless an environment has a shorter one.
+const MaxShareExpiry = 7 * 24 * time.Hour
+
+// Credential sources of `Profile.Credentials`.
+const (
+	CredentialsEnv    = "env"
+	CredentialsFile   = "file"
+	CredentialsHelper = "helper"
+	CredentialsStatic = "static"
+)
+
+// CredentialSources in default order: environment variables, shared credentials file, helper command,
+// then key and secret of the profile.
+var CredentialSources = []string{CredentialsEnv, CredentialsFile, CredentialsHelper, CredentialsStatic}
+
+// Bucket addressing styles of `Profile.Addressing`.
+const (
+	AddressingAuto        = "auto"
+	AddressingPath        = "path"
+	AddressingVirtualHost = "virtual-host"
+)
+
+// Addressings accepted by `Profile.Addressing`, empty means `AddressingAuto`.
+var Addressings = []string{AddressingAuto, AddressingPath, AddressingVirtualHost}
+
+// Config contains a default profile as top-level keys and optional named profiles.
+type Config struct {
+	Profile
+
+	// DefaultProfile name used when no profile is selected explicitly.
+	DefaultProfile string `json:"default_profile,omitempty"`
+
+	// Profiles by name, e.g. "do-sgp1" or "minio".
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// overrides from environment variables, applied to selected profile.
+	overrides Profile
+}
+
+// Default configuration from compiled-in `service` values.
+func Default() Config {
+	return Config{
+		Profile: Profile{
+			Endpoint:     service.SpaceEndpoint,
+			Key:          service.SpaceKey,
+			Secret:       service.SpaceSecret,
+			Environments: service.Environments(),
+			EnvSettings:  map[string]EnvSettings{"live": {Protected: true, MaxShareExpiry: "24h"}},
+		},
+	}
+}
+
+// DefaultPath to the config file, `$SPACE_CONFIG` or `space/config.json` under user's config dir.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "space", "config.json"), nil
+}
+
+// Load config from `path`, falling back to `DefaultPath` when empty.
+// A missing file is only an error if `path` is given explicitly.
+func Load(path string) (cfg Config, err error) {
+	cfg = Default()
+
+	explicit := path != ""
+	if !explicit {
+		path, err = DefaultPath()
+		if err != nil {
+			return cfg, err
+		}
+		explicit = os.Getenv(EnvConfig) != ""
+	}
+
+	file, err := ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		err = nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Profile = cfg.Profile.Merge(file.Profile)
+	cfg.DefaultProfile = file.DefaultProfile
+	cfg.Profiles = file.Profiles
+
+	cfg.overrides, err = FromEnv()
+	return cfg, err
+}
+
+// Save config as JSON file at `path`, creating its directory. Config from `Load` includes compiled-in values
+// and environment overrides, so edit the one from `ReadFile` instead, see `Edit`.
+func (c Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// Edit a profile of the config file at `path`, or `DefaultPath` if empty, and save it.
+// Profile is selected like `Select`, but without environment overrides. Missing file is created.
+func Edit(path, name string, edit func(*Profile) error) error {
+	if path == "" {
+		var err error
+		if path, err = DefaultPath(); err != nil {
+			return err
+		}
+	}
+	cfg, err := ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+	if name == "" {
+		name = cfg.DefaultProfile
+	}
+	if name == "" || name == DefaultProfileName {
+		if err = edit(&cfg.Profile); err != nil {
+			return err
+		}
+		return cfg.Save(path)
+	}
+
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return fmt.Errorf("Invalid profile %v, possible values: %v", name, cfg.ProfileNames())
+	}
+	if err = edit(&p); err != nil {
+		return err
+	}
+	cfg.Profiles[name] = p
+	return cfg.Save(path)
+}
+
+// ReadFile parses a JSON config file.
+func ReadFile(path string) (cfg Config, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("Invalid config file %v: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// FromEnv reads profile overrides from `SPACE_*` environment variables.
+// `SPACE_ENVIRONMENTS` is a list of pairs, e.g. "dev=dev.bucket,live=live.bucket".
+func FromEnv() (p Profile, err error) {
+	p.Endpoint = os.Getenv(EnvEndpoint)
+	p.Key = os.Getenv(EnvKey)
+	p.Secret = os.Getenv(EnvSecret)
+	p.Region = os.Getenv(EnvRegion)
+	p.CACert = os.Getenv(EnvCACert)
+	p.CredentialsHelper = os.Getenv(EnvHelper)
+	p.Environments, err = ParseEnvironments(os.Getenv(EnvEnvironments))
+	return p, err
+}
+
+// ParseEnvironments from comma separated `env=bucket` pairs.
+func ParseEnvironments(text string) (map[string]string, error) {
+	envs := map[string]string{}
+	for _, pair := range strings.Split(text, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		keyval := strings.SplitN(pair, "=", 2)
+		if len(keyval) != 2 || strings.TrimSpace(keyval[0]) == "" || strings.TrimSpace(keyval[1]) == "" {
+			return nil, fmt.Errorf("Invalid environment %q, want env=bucket", pair)
+		}
+		envs[strings.TrimSpace(keyval[0])] = strings.TrimSpace(keyval[1])
+	}
+	return envs, nil
+}
+
+// ProfileNames sorted alphabetically, including `DefaultProfileName`.
+func (c Config) ProfileNames() []string {
+	names := []string{DefaultProfileName}
+	for name := range c.Profiles {
+		if name != DefaultProfileName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Select profile by name with environment overrides applied.
+// Empty name falls back to `$SPACE_PROFILE`, then `DefaultProfile`, then top-level profile.
+func (c Config) Select(name string) (Profile, error) {
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+	if name == "" {
+		name = c.DefaultProfile
+	}
+
+	p := c.Profile
+	if name != "" && name != DefaultProfileName {
+		named, ok := c.Profiles[name]
+		if !ok {
+			return p, fmt.Errorf("Invalid profile %v, possible values: %v", name, c.ProfileNames())
+		}
+		// Built-in environment settings, e.g. protected live, apply unless the profile overrides them.
+		p = Profile{EnvSettings: Default().EnvSettings}.Merge(named)
+	}
+	return p.Merge(c.overrides), nil
+}
+
+// Merge non-empty values of `other` into a copy of `p`.
+func (p Profile) Merge(other Profile) Profile {
+	if other.Endpoint != "" {
+		p.Endpoint = other.Endpoint
+	}
+	if other.Key != "" {
+		p.Key = other.Key
+	}
+	if other.Secret != "" {
+		p.Secret = other.Secret
+	}
+	if len(other.Credentials) > 0 {
+		p.Credentials = other.Credentials
+	}
+	if other.CredentialsFile != "" {
+		p.CredentialsFile = other.CredentialsFile
+	}
+	if other.CredentialsProfile != "" {
+		p.CredentialsProfile = other.CredentialsProfile
+	}
+	if other.CredentialsHelper != "" {
+		p.CredentialsHelper = other.CredentialsHelper
+	}
+	if other.Insecure {
+		p.Insecure = true
+	}
+	if other.CACert != "" {
+		p.CACert = other.CACert
+	}
+	if other.ClientCert != "" {
+		p.ClientCert = other.ClientCert
+	}
+	if other.ClientKey != "" {
+		p.ClientKey = other.ClientKey
+	}
+	if other.Addressing != "" {
+		p.Addressing = other.Addressing
+	}
+	if other.Region != "" {
+		p.Region = other.Region
+	}
+
+	envs := make(map[string]string, len(p.Environments)+len(other.Environments))
+	for env, bucket := range p.Environments {
+		envs[env] = bucket
+	}
+	for env, bucket := range other.Environments {
+		envs[env] = bucket
+	}
+	p.Environments = envs
+
+	settings := make(map[string]EnvSettings, len(p.EnvSettings)+len(other.EnvSettings))
+	for env, s := range p.EnvSettings {
+		settings[env] = s
+	}
+	for env, s := range other.EnvSettings {
+		settings[env] = s
+	}
+	p.EnvSettings = settings
+	return p
+}
+
+// EnvNames sorted alphabetically.
+func (p Profile) EnvNames() []string {
+	names := make([]string, 0, len(p.Environments))
+	for env := range p.Environments {
+		names = append(names, env)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Protected tells whether destructive commands in an environment require confirmation.
+func (p Profile) Protected(env string) bool {
+	return p.EnvSettings[env].Protected
+}
+
+// ShareExpiry is the longest expiry of presigned URLs to objects of an environment, see `EnvSettings.MaxShareExpiry`.
+func (p Profile) ShareExpiry(env string) (time.Duration, error) {
+	text := p.EnvSettings[env].MaxShareExpiry
+	if text == "" {
+		return MaxShareExpiry, nil
+	}
+	expiry, err := time.ParseDuration(text)
+	if err != nil || expiry <= 0 {
+		return 0, fmt.Errorf("Invalid max_share_expiry %q of environment %v, want a duration like 24h", text, env)
+	}
+	if expiry > MaxShareExpiry {
+		expiry = MaxShareExpiry
+	}
+	return expiry, nil
+}
+
+// Bucket name from given environment name.
+func (p Profile) Bucket(env string) (string, error) {
+	bucket, ok := p.Environments[env]
+	if !ok {
+		return "", fmt.Errorf("Invalid environment %v, possible values: %v", env, p.EnvNames())
+	}
+	return bucket, nil
+}
@@ -0,0 +1,200 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lebenasa/space/config"
+)
+
+func setupConfigFile(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "space-config")
+	if err != nil {
+		t.Fatalf("setup config dir fail: %v", err)
+	}
+	path := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("setup config file fail: %v", err)
+	}
+	return path, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestParseEnvironments(t *testing.T) {
+	envs, err := config.ParseEnvironments("dev=dev.bucket, live = live.bucket")
+	if err != nil {
+		t.Errorf("case 1 got error %v", err)
+	}
+	if envs["dev"] != "dev.bucket" || envs["live"] != "live.bucket" {
+		t.Errorf("case 1 got %v", envs)
+	}
+
+	envs, err = config.ParseEnvironments("")
+	if err != nil || len(envs) != 0 {
+		t.Errorf("case 2 got %v, %v, want empty map", envs, err)
+	}
+
+	if _, err = config.ParseEnvironments("dev"); err == nil {
+		t.Error("case 3 got no error, want error")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path, teardown := setupConfigFile(t, `{
+		"endpoint": "file.endpoint",
+		"key": "file-key",
+		"environments": {"dev": "file.dev", "live": "file.live"}
+	}`)
+	defer teardown()
+
+	os.Setenv(config.EnvKey, "env-key")
+	os.Setenv(config.EnvEnvironments, "dev=env.dev")
+	defer os.Unsetenv(config.EnvKey)
+	defer os.Unsetenv(config.EnvEnvironments)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("case 1 got error %v", err)
+	}
+	profile, err := cfg.Select("")
+	if err != nil {
+		t.Fatalf("case 1 got error %v", err)
+	}
+	if profile.Endpoint != "file.endpoint" {
+		t.Errorf("case 1 got endpoint %v, want file.endpoint", profile.Endpoint)
+	}
+	if profile.Key != "env-key" {
+		t.Errorf("case 1 got key %v, want env-key", profile.Key)
+	}
+	if bucket, _ := profile.Bucket("dev"); bucket != "env.dev" {
+		t.Errorf("case 1 got dev bucket %v, want env.dev", bucket)
+	}
+	if bucket, _ := profile.Bucket("live"); bucket != "file.live" {
+		t.Errorf("case 1 got live bucket %v, want file.live", bucket)
+	}
+	if _, err := profile.Bucket("foo"); err == nil {
+		t.Error("case 1 got no error for unknown env, want error")
+	}
+
+	if _, err := config.Load(path + ".missing"); err == nil {
+		t.Error("case 2 got no error, want error")
+	}
+
+	bad, teardownBad := setupConfigFile(t, "{")
+	defer teardownBad()
+	if _, err := config.Load(bad); err == nil {
+		t.Error("case 3 got no error, want error")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	path, teardown := setupConfigFile(t, `{
+		"endpoint": "default.endpoint",
+		"default_profile": "do",
+		"profiles": {
+			"do": {"endpoint": "sgp1.digitaloceanspaces.com", "environments": {"dev": "do.dev"}, "env_settings": {"dev": {"protected": true}}},
+			"minio": {"endpoint": "localhost:9000", "insecure": true, "addressing": "path", "region": "us-east-1", "ca_cert": "ca.pem",
+				"credentials": ["helper", "static"], "credentials_helper": "pass space/minio"}
+		}
+	}`)
+	defer teardown()
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("setup load got error %v", err)
+	}
+
+	profile, err := cfg.Select("")
+	if err != nil || profile.Endpoint != "sgp1.digitaloceanspaces.com" {
+		t.Errorf("case 1 got %v, %v, want default_profile", profile.Endpoint, err)
+	}
+	if expiry, _ := profile.ShareExpiry("live"); !profile.Protected("live") || !profile.Protected("dev") || expiry != 24*time.Hour {
+		t.Errorf("case 1 got settings %v, want built-in live settings and protected dev", profile.EnvSettings)
+	}
+
+	profile, err = cfg.Select("minio")
+	if err != nil || profile.Endpoint != "localhost:9000" || !profile.Insecure || profile.Addressing != config.AddressingPath ||
+		profile.Region != "us-east-1" || profile.CACert != "ca.pem" || len(profile.Credentials) != 2 || profile.CredentialsHelper != "pass space/minio" {
+		t.Errorf("case 2 got %+v, %v, want insecure minio profile", profile, err)
+	}
+
+	profile, err = cfg.Select(config.DefaultProfileName)
+	if err != nil || profile.Endpoint != "default.endpoint" {
+		t.Errorf("case 3 got %v, %v, want top-level profile", profile.Endpoint, err)
+	}
+
+	os.Setenv(config.EnvProfile, "minio")
+	profile, err = cfg.Select("")
+	os.Unsetenv(config.EnvProfile)
+	if err != nil || profile.Endpoint != "localhost:9000" {
+		t.Errorf("case 4 got %v, %v, want profile from environment", profile.Endpoint, err)
+	}
+
+	if _, err = cfg.Select("foo"); err == nil {
+		t.Error("case 5 got no error, want error")
+	}
+}
+
+func TestEdit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "space-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "space", "config.json")
+
+	err = config.Edit(path, "", func(p *config.Profile) error {
+		p.Environments = map[string]string{"staging": "staging.bucket"}
+		p.EnvSettings = map[string]config.EnvSettings{"staging": {Protected: true}}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("case 1 got error %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("case 1 got error %v", err)
+	}
+	profile, err := cfg.Select("")
+	if bucket, _ := profile.Bucket("staging"); err != nil || bucket != "staging.bucket" || !profile.Protected("staging") {
+		t.Errorf("case 1 got %+v, %v, want protected staging", profile, err)
+	}
+	if !profile.Protected("live") || profile.Protected("dev") {
+		t.Errorf("case 2 got %+v, want only live protected by default", profile.EnvSettings)
+	}
+
+	if err = config.Edit(path, "minio", func(p *config.Profile) error { return nil }); err == nil {
+		t.Error("case 3 got no error, want invalid profile")
+	}
+	file, _ := config.ReadFile(path)
+	if file.Endpoint != "" || file.Key != "" {
+		t.Errorf("case 4 got %+v, want only edited values saved", file.Profile)
+	}
+}
+
+func TestShareExpiry(t *testing.T) {
+	profile := config.Default().Profile
+	profile.EnvSettings["staging"] = config.EnvSettings{MaxShareExpiry: "30d"}
+	profile.EnvSettings["archive"] = config.EnvSettings{MaxShareExpiry: "720h"}
+
+	cases := []struct {
+		env  string
+		want time.Duration
+		err  bool
+	}{
+		{"live", 24 * time.Hour, false},
+		{"dev", config.MaxShareExpiry, false},
+		{"archive", config.MaxShareExpiry, false},
+		{"staging", 0, true},
+	}
+	for i, c := range cases {
+		expiry, err := profile.ShareExpiry(c.env)
+		if expiry != c.want || (err != nil) != c.err {
+			t.Errorf("case %v got %v, %v, want %v", i+1, expiry, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package space
+
+// Server side copies, keeping metadata and tags of the source object.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MaxCopySize of a single server side copy, larger objects are copied in parts.
+const MaxCopySize = 5 << 30
+
+// Copy an object server side, e.g. between buckets of two environments. Content type, user metadata and tags
+// of the source object are kept, `metadata` is added to its user metadata. Objects larger than `MaxCopySize`
+// are copied in parts concurrently, see `WithPartSize` and `WithJobs`.
+// With `WithVerify`, the copy is checked against the source object.
+func (s Space) Copy(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) error {
+	info, err := s.backend.StatObject(ctx, srcBucket, srcObject, StatObjectOptions{})
+	if err != nil {
+		return err
+	}
+	tags, err := s.backend.GetObjectTagging(ctx, srcBucket, srcObject)
+	if err != nil {
+		return err
+	}
+
+	options := copyOptions(info, metadata)
+	options.UserTags = tags
+	if info.Size > MaxCopySize {
+		err = s.copyParts(ctx, srcBucket, srcObject, dstBucket, dstObject, info.Size, options)
+	} else {
+		err = s.backend.CopyObject(ctx, srcBucket, srcObject, dstBucket, dstObject, options)
+	}
+	if err != nil {
+		return err
+	}
+	if s.verify {
+		return s.verifyCopy(ctx, srcBucket, info, dstBucket, dstObject)
+	}
+	return nil
+}
+
+// Move an object server side, removing the source once copied, see `Copy`.
+func (s Space) Move(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string) error {
+	if srcBucket == dstBucket && srcObject == dstObject {
+		return fmt.Errorf("Invalid move, %v/%v is both source and destination", srcBucket, srcObject)
+	}
+	if err := s.Copy(ctx, srcBucket, srcObject, dstBucket, dstObject, nil); err != nil {
+		return err
+	}
+	return s.backend.RemoveObject(ctx, srcBucket, srcObject)
+}
+
+// CopyPrefix copies every object under `srcPrefix` selected by `WithFilter` concurrently, see `Copy` and `WithJobs`.
+// Object names relative to `srcPrefix` are kept under `dstPrefix`, both are treated as folders.
+// Failed objects don't stop the copy, they're reported with `WithReport` and returned as `*TransferError`,
+// where `TransferResult.Path` is the source object as "{bucket}/{object}".
+func (s Space) CopyPrefix(ctx context.Context, srcBucket, srcPrefix, dstBucket, dstPrefix string) (objectNames []string, err error) {
+	return s.copyPrefix(ctx, srcBucket, srcPrefix, dstBucket, dstPrefix, false)
+}
+
+// MovePrefix moves every object under `srcPrefix` selected by `WithFilter`, see `CopyPrefix` and `Move`.
+func (s Space) MovePrefix(ctx context.Context, srcBucket, srcPrefix, dstBucket, dstPrefix string) (objectNames []string, err error) {
+	return s.copyPrefix(ctx, srcBucket, srcPrefix, dstBucket, dstPrefix, true)
+}
+
+func (s Space) copyPrefix(ctx context.Context, srcBucket, srcPrefix, dstBucket, dstPrefix string, move bool) (objectNames []string, err error) {
+	srcPrefix, dstPrefix = folderPrefix(srcPrefix), folderPrefix(dstPrefix)
+
+	srcObjects, dstObjects := []string{}, []string{}
+	err = s.WalkObjects(ctx, srcBucket, ListOptions{Prefix: srcPrefix, Recursive: true}, func(object ObjectInfo) error {
+		srcObjects = append(srcObjects, object.Key)
+		dstObjects = append(dstObjects, dstPrefix+strings.TrimPrefix(object.Key, srcPrefix))
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	copyObject := func(srcObject, dstObject string) error {
+		return s.Copy(ctx, srcBucket, srcObject, dstBucket, dstObject, nil)
+	}
+	if move {
+		copyObject = func(srcObject, dstObject string) error {
+			return s.Move(ctx, srcBucket, srcObject, dstBucket, dstObject)
+		}
+	}
+	return s.copyObjects(ctx, srcBucket, srcObjects, dstObjects, copyObject)
+}
+
+// copyObjects from a bucket concurrently with `copyObject`, returning copied destination objects.
+func (s Space) copyObjects(ctx context.Context, srcBucket string, srcObjects, dstObjects []string, copyObject func(srcObject, dstObject string) error) (objectNames []string, err error) {
+	planned := make([]TransferResult, len(srcObjects))
+	for i, srcObject := range srcObjects {
+		planned[i] = TransferResult{Path: srcBucket + "/" + srcObject, ObjectName: dstObjects[i]}
+	}
+
+	results, err := s.transfer(ctx, planned, func(result TransferResult) TransferResult {
+		result.Err = copyObject(strings.TrimPrefix(result.Path, srcBucket+"/"), result.ObjectName)
+		return result
+	})
+	for _, result := range results {
+		if result.Err == nil {
+			objectNames = append(objectNames, result.ObjectName)
+		}
+	}
+	return
+}
+
+// copyParts of an object concurrently into a multipart upload, which is aborted on failure.
+func (s Space) copyParts(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string, size int64, options PutObjectOptions) error {
+	uploadID, err := s.backend.NewMultipartUpload(ctx, dstBucket, dstObject, options)
+	if err != nil {
+		return err
+	}
+
+	partSize := s.partSizeFor(size)
+	count := int((size + partSize - 1) / partSize)
+	jobs := s.jobs
+	if jobs <= 0 {
+		jobs = DefaultJobs
+	}
+
+	partCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parts := make([]CompletePart, count)
+	failOnce := sync.Once{}
+	sem := make(chan struct{}, jobs)
+	wg := sync.WaitGroup{}
+	for i := 0; i < count; i++ {
+		offset := int64(i) * partSize
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, offset, length int64) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			part, pErr := s.backend.CopyObjectPart(partCtx, srcBucket, srcObject, dstBucket, dstObject, uploadID, i+1, offset, length)
+			if pErr != nil {
+				failOnce.Do(func() {
+					err = fmt.Errorf("Failed to copy part %v of %v: %w", i+1, srcObject, pErr)
+					cancel()
+				})
+				return
+			}
+			parts[i] = part
+		}(i, offset, length)
+	}
+	wg.Wait()
+
+	if err == nil {
+		err = s.backend.CompleteMultipartUpload(ctx, dstBucket, dstObject, uploadID, parts)
+	}
+	if err != nil {
+		s.backend.AbortMultipartUpload(context.Background(), dstBucket, dstObject, uploadID)
+	}
+	return err
+}
+
+// copyOptions keeping content headers and user metadata of an object, with `metadata` added.
+func copyOptions(info ObjectInfo, metadata map[string]string) PutObjectOptions {
+	userMetadata := make(map[string]string, len(info.UserMetadata)+len(metadata))
+	for key, val := range info.UserMetadata {
+		userMetadata[key] = val
+	}
+	for key, val := range metadata {
+		userMetadata[key] = val
+	}
+	return PutObjectOptions{
+		ContentType:        info.ContentType,
+		ContentEncoding:    info.Metadata.Get("Content-Encoding"),
+		ContentDisposition: info.Metadata.Get("Content-Disposition"),
+		ContentLanguage:    info.Metadata.Get("Content-Language"),
+		CacheControl:       info.Metadata.Get("Cache-Control"),
+		UserMetadata:       userMetadata,
+	}
+}
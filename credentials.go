@@ -0,0 +1,235 @@
+package space
+
+// Credential providers looked up in order, so keys don't have to be compiled in.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/lebenasa/space/config"
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+// CredentialProvider retrieves credentials and tells when they have to be retrieved again.
+// Retrieved credentials are cached until they expire.
+type CredentialProvider = credentials.Provider
+
+// CredentialValue is a key, secret and optional session token.
+type CredentialValue = credentials.Value
+
+// DefaultExpiryWindow refreshes expiring credentials this long before they expire.
+const DefaultExpiryWindow = time.Minute
+
+// WithCredentials from `provider` instead of the ones of the profile.
+func WithCredentials(provider CredentialProvider) Option {
+	return func(o *settings) {
+		o.credentials = provider
+	}
+}
+
+// ChainProvider uses the first provider with credentials. A provider failing is skipped,
+// but its error is returned if no other provider has credentials.
+// Without any credentials, requests are anonymous.
+type ChainProvider struct {
+	Providers []CredentialProvider
+	current   CredentialProvider
+}
+
+// Retrieve from the first provider with credentials.
+func (c *ChainProvider) Retrieve() (CredentialValue, error) {
+	c.current = nil
+	errs := []string{}
+	for _, provider := range c.Providers {
+		value, err := provider.Retrieve()
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if value.AccessKeyID == "" || value.SecretAccessKey == "" {
+			continue
+		}
+		c.current = provider
+		return value, nil
+	}
+	if len(errs) > 0 {
+		err := fmt.Errorf("No credentials found: %v", strings.Join(errs, "; "))
+		return CredentialValue{}, &Error{Kind: ErrAccessDenied, Err: err}
+	}
+	return CredentialValue{SignerType: credentials.SignatureAnonymous}, nil
+}
+
+// IsExpired if credentials of the provider in use expired, or none was found yet.
+func (c *ChainProvider) IsExpired() bool {
+	return c.current == nil || c.current.IsExpired()
+}
+
+// credentialChain of profile's `config.Profile.Credentials` sources.
+func credentialChain(profile config.Profile) (CredentialProvider, error) {
+	sources := profile.Credentials
+	if len(sources) == 0 {
+		sources = config.CredentialSources
+	}
+
+	chain := &ChainProvider{}
+	for _, source := range sources {
+		switch source {
+		case config.CredentialsEnv:
+			chain.Providers = append(chain.Providers, &EnvProvider{}, &credentials.EnvAWS{})
+		case config.CredentialsFile:
+			chain.Providers = append(chain.Providers, &FileProvider{Filename: profile.CredentialsFile, Profile: profile.CredentialsProfile})
+		case config.CredentialsHelper:
+			if profile.CredentialsHelper != "" {
+				chain.Providers = append(chain.Providers, &HelperProvider{Command: profile.CredentialsHelper})
+			}
+		case config.CredentialsStatic:
+			chain.Providers = append(chain.Providers, &credentials.Static{Value: CredentialValue{
+				AccessKeyID:     profile.Key,
+				SecretAccessKey: profile.Secret,
+				SignerType:      credentials.SignatureV4,
+			}})
+		default:
+			return nil, fmt.Errorf("Invalid credential source %v, possible values: %v", source, config.CredentialSources)
+		}
+	}
+	return chain, nil
+}
+
+// EnvProvider reads `SPACE_KEY`, `SPACE_SECRET` and `SPACE_SESSION_TOKEN` environment variables.
+// Credentials never expire.
+type EnvProvider struct {
+	retrieved bool
+}
+
+// Retrieve from environment variables.
+func (p *EnvProvider) Retrieve() (CredentialValue, error) {
+	p.retrieved = true
+	return CredentialValue{
+		AccessKeyID:     os.Getenv(config.EnvKey),
+		SecretAccessKey: os.Getenv(config.EnvSecret),
+		SessionToken:    os.Getenv(config.EnvSessionToken),
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+// IsExpired until retrieved once.
+func (p *EnvProvider) IsExpired() bool {
+	return !p.retrieved
+}
+
+// FileProvider reads an AWS shared credentials file, `AWS_SHARED_CREDENTIALS_FILE` or "~/.aws/credentials" by default.
+// Missing file has no credentials. Credentials never expire.
+type FileProvider struct {
+	Filename string
+	// Profile in the file, `AWS_PROFILE` or "default" if empty.
+	Profile   string
+	retrieved bool
+}
+
+// Retrieve from the file, if it exists.
+func (p *FileProvider) Retrieve() (CredentialValue, error) {
+	p.retrieved = true
+	filename := p.Filename
+	if filename == "" {
+		filename = os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	}
+	if filename == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return CredentialValue{}, nil
+		}
+		filename = filepath.Join(home, ".aws", "credentials")
+	}
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return CredentialValue{}, nil
+	}
+
+	value, err := credentials.NewFileAWSCredentials(filename, p.Profile).Get()
+	if err != nil {
+		return value, fmt.Errorf("Invalid credentials file %v: %v", filename, err)
+	}
+	return value, nil
+}
+
+// IsExpired until retrieved once.
+func (p *FileProvider) IsExpired() bool {
+	return !p.retrieved
+}
+
+// HelperProvider runs a command printing credentials as JSON, the same as AWS `credential_process`:
+//
+//	{"Version": 1, "AccessKeyId": "...", "SecretAccessKey": "...", "SessionToken": "...", "Expiration": "2020-01-02T15:04:05Z"}
+//
+// Command is run by the shell, its stderr is passed through so it can prompt the user.
+// Credentials without expiration are kept, others are retrieved again `ExpiryWindow` before they expire.
+type HelperProvider struct {
+	Command string
+	// ExpiryWindow is `DefaultExpiryWindow` if zero.
+	ExpiryWindow time.Duration
+
+	credentials.Expiry
+	retrieved bool
+	expires   bool
+}
+
+// helperOutput of a credentials helper command.
+type helperOutput struct {
+	Version         int
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      *time.Time
+}
+
+// Retrieve by running the command.
+func (p *HelperProvider) Retrieve() (CredentialValue, error) {
+	p.retrieved = false
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.Command(shell, flag, p.Command)
+	cmd.Stderr = os.Stderr
+	stdout := bytes.Buffer{}
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return CredentialValue{}, fmt.Errorf("Credentials helper %q failed: %v", p.Command, err)
+	}
+
+	out := helperOutput{}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return CredentialValue{}, fmt.Errorf("Invalid output of credentials helper %q: %v", p.Command, err)
+	}
+	if out.Version > 1 {
+		return CredentialValue{}, fmt.Errorf("Invalid output of credentials helper %q: unsupported version %v", p.Command, out.Version)
+	}
+	if out.AccessKeyID == "" || out.SecretAccessKey == "" {
+		return CredentialValue{}, fmt.Errorf("Invalid output of credentials helper %q: missing AccessKeyId or SecretAccessKey", p.Command)
+	}
+
+	p.retrieved, p.expires = true, out.Expiration != nil
+	if p.expires {
+		window := p.ExpiryWindow
+		if window == 0 {
+			window = DefaultExpiryWindow
+		}
+		p.SetExpiration(*out.Expiration, window)
+	}
+	return CredentialValue{
+		AccessKeyID:     out.AccessKeyID,
+		SecretAccessKey: out.SecretAccessKey,
+		SessionToken:    out.SessionToken,
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+// IsExpired until retrieved, then once credentials with expiration are about to expire.
+func (p *HelperProvider) IsExpired() bool {
+	return !p.retrieved || (p.expires && p.Expiry.IsExpired())
+}
@@ -0,0 +1,152 @@
+package space
+
+// Connection settings of S3 compatible endpoints: TLS, bucket addressing and region.
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/lebenasa/space/config"
+	"github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+// Option overrides connection settings of the selected profile, see `New`.
+type Option func(*settings)
+
+// settings of a new Space client, a profile and credentials overriding the ones of the profile.
+type settings struct {
+	profile     config.Profile
+	credentials CredentialProvider
+}
+
+// WithInsecure uses plain HTTP instead of HTTPS, e.g. for a local MinIO container.
+func WithInsecure(insecure bool) Option {
+	return func(o *settings) {
+		o.profile.Insecure = insecure
+	}
+}
+
+// WithCACert trusts certificates in a PEM bundle besides system roots, e.g. for a private CA.
+func WithCACert(path string) Option {
+	return func(o *settings) {
+		o.profile.CACert = path
+	}
+}
+
+// WithClientCert authenticates with a certificate and its key, both PEM files.
+func WithClientCert(certFile, keyFile string) Option {
+	return func(o *settings) {
+		o.profile.ClientCert = certFile
+		o.profile.ClientKey = keyFile
+	}
+}
+
+// WithAddressing of buckets, one of `config.Addressings`.
+func WithAddressing(addressing string) Option {
+	return func(o *settings) {
+		o.profile.Addressing = addressing
+	}
+}
+
+// WithRegion of the endpoint, skipping region detection.
+func WithRegion(region string) Option {
+	return func(o *settings) {
+		o.profile.Region = region
+	}
+}
+
+// newMinioClient connecting to profile's endpoint, with credentials from `settings` or profile's `credentialChain`.
+func newMinioClient(o settings) (*minio.Client, error) {
+	profile := o.profile
+	secure := !profile.Insecure
+	lookup, err := bucketLookup(profile.Addressing)
+	if err != nil {
+		return nil, err
+	}
+	provider := o.credentials
+	if provider == nil {
+		if provider, err = credentialChain(profile); err != nil {
+			return nil, err
+		}
+	}
+	client, err := minio.NewWithOptions(profile.Endpoint, &minio.Options{
+		Creds:        credentials.New(provider),
+		Secure:       secure,
+		Region:       profile.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if secure && (profile.CACert != "" || profile.ClientCert != "" || profile.ClientKey != "") {
+		transport, err := tlsTransport(profile)
+		if err != nil {
+			return nil, err
+		}
+		client.SetCustomTransport(transport)
+	}
+	return client, nil
+}
+
+// bucketLookup of `config.Profile.Addressing`.
+func bucketLookup(addressing string) (minio.BucketLookupType, error) {
+	switch addressing {
+	case "", config.AddressingAuto:
+		return minio.BucketLookupAuto, nil
+	case config.AddressingPath:
+		return minio.BucketLookupPath, nil
+	case config.AddressingVirtualHost:
+		return minio.BucketLookupDNS, nil
+	}
+	return minio.BucketLookupAuto, fmt.Errorf("Invalid addressing %v, possible values: %v", addressing, config.Addressings)
+}
+
+// tlsTransport trusting profile's CA bundle and presenting its client certificate.
+func tlsTransport(profile config.Profile) (*http.Transport, error) {
+	rt, err := minio.DefaultTransport(true)
+	if err != nil {
+		return nil, err
+	}
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected transport %T", rt)
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if transport.TLSClientConfig != nil {
+		tlsConfig = transport.TLSClientConfig.Clone()
+	}
+
+	if profile.CACert != "" {
+		pem, err := ioutil.ReadFile(profile.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Invalid CA certificate %v, no PEM certificate found", profile.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if profile.ClientCert != "" || profile.ClientKey != "" {
+		if profile.ClientCert == "" || profile.ClientKey == "" {
+			return nil, fmt.Errorf("Invalid client certificate, both certificate and key are required")
+		}
+		cert, err := tls.LoadX509KeyPair(profile.ClientCert, profile.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
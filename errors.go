@@ -0,0 +1,149 @@
+package space
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// Kinds of failure, test with `errors.Is`. Errors returned by Space keep the S3 error response,
+// so `errors.As` with `minio.ErrorResponse` still works.
+var (
+	ErrNotFound       = errors.New("Not found")
+	ErrAccessDenied   = errors.New("Access denied")
+	ErrBucketNotFound = errors.New("Bucket not found")
+	ErrInvalidEnv     = errors.New("Invalid environment")
+	ErrConflict       = errors.New("Conflict")
+)
+
+// Error of a known `Kind`, e.g. `ErrNotFound`, wrapping the original error.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether `target` is the kind of this error.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// errorCodes of S3 error responses mapped to error kinds.
+var errorCodes = map[string]error{
+	"NoSuchKey":               ErrNotFound,
+	"NoSuchUpload":            ErrNotFound,
+	"NotFound":                ErrNotFound,
+	"NoSuchBucket":            ErrBucketNotFound,
+	"AccessDenied":            ErrAccessDenied,
+	"AllAccessDisabled":       ErrAccessDenied,
+	"InvalidAccessKeyId":      ErrAccessDenied,
+	"SignatureDoesNotMatch":   ErrAccessDenied,
+	"ExpiredToken":            ErrAccessDenied,
+	"BucketAlreadyExists":     ErrConflict,
+	"BucketAlreadyOwnedByYou": ErrConflict,
+	"BucketNotEmpty":          ErrConflict,
+	"OperationAborted":        ErrConflict,
+	"PreconditionFailed":      ErrConflict,
+}
+
+// translateError into `*Error` if its kind is known, otherwise it's returned as is.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	var kind error
+	var resp minio.ErrorResponse
+	switch {
+	case errors.As(err, &resp):
+		kind = errorCodes[resp.Code]
+		if kind == nil {
+			switch resp.StatusCode {
+			case http.StatusNotFound:
+				kind = ErrNotFound
+			case http.StatusForbidden:
+				kind = ErrAccessDenied
+			case http.StatusConflict, http.StatusPreconditionFailed:
+				kind = ErrConflict
+			}
+		}
+	case os.IsPermission(err):
+		kind = ErrAccessDenied
+	}
+	if kind == nil {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// ObjectError is the failure of a single object in a batch operation.
+type ObjectError struct {
+	ObjectName string
+	// Code is the S3 error code, e.g. AccessDenied, if known.
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e ObjectError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%v: %v", e.ObjectName, e.Message)
+	}
+	return fmt.Sprintf("%v: %v (%v)", e.ObjectName, e.Message, e.Code)
+}
+
+func (e ObjectError) Unwrap() error {
+	return e.Err
+}
+
+// newObjectError with code and message taken from S3 error response, if any.
+func newObjectError(objectName string, err error) ObjectError {
+	e := ObjectError{ObjectName: objectName, Message: err.Error(), Err: err}
+	var resp minio.ErrorResponse
+	if errors.As(err, &resp) && resp.Code != "" {
+		e.Code = resp.Code
+		if resp.Message != "" {
+			e.Message = resp.Message
+		}
+	}
+	return e
+}
+
+// BatchError lists every object failed in a batch operation like `RemoveObjects`, other objects succeeded.
+type BatchError struct {
+	Op     string
+	Total  int
+	Failed []ObjectError
+}
+
+func (e *BatchError) Error() string {
+	lines := []string{fmt.Sprintf("Failed to %v %v of %v object(s):", e.Op, len(e.Failed), e.Total)}
+	for _, failed := range e.Failed {
+		lines = append(lines, failed.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FailedNames of objects, so callers can tell them apart from succeeded ones.
+func (e *BatchError) FailedNames() map[string]bool {
+	names := map[string]bool{}
+	for _, failed := range e.Failed {
+		names[failed.ObjectName] = true
+	}
+	return names
+}
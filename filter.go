@@ -0,0 +1,131 @@
+package space
+
+// Object filters shared by listing, download, removal and sync.
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Filter reports whether an object is selected. Object tags are only fetched, using `tags`, by filters needing them.
+type Filter func(object ObjectInfo, tags func() (map[string]string, error)) (bool, error)
+
+// Glob selects objects whose name matches a `path.Match` pattern.
+// Pattern without "/" is matched against the last element of object's name, e.g. "*.tar.gz".
+func Glob(pattern string) Filter {
+	return func(object ObjectInfo, tags func() (map[string]string, error)) (bool, error) {
+		name := object.Key
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(name)
+		}
+		return path.Match(pattern, name)
+	}
+}
+
+// LargerThan selects objects bigger than `size` bytes.
+func LargerThan(size int64) Filter {
+	return func(object ObjectInfo, tags func() (map[string]string, error)) (bool, error) {
+		return object.Size > size, nil
+	}
+}
+
+// SmallerThan selects objects smaller than `size` bytes.
+func SmallerThan(size int64) Filter {
+	return func(object ObjectInfo, tags func() (map[string]string, error)) (bool, error) {
+		return object.Size < size, nil
+	}
+}
+
+// OlderThan selects objects last modified more than `age` ago.
+func OlderThan(age time.Duration) Filter {
+	cutoff := time.Now().Add(-age)
+	return func(object ObjectInfo, tags func() (map[string]string, error)) (bool, error) {
+		return object.LastModified.Before(cutoff), nil
+	}
+}
+
+// NewerThan selects objects last modified less than `age` ago.
+func NewerThan(age time.Duration) Filter {
+	cutoff := time.Now().Add(-age)
+	return func(object ObjectInfo, tags func() (map[string]string, error)) (bool, error) {
+		return object.LastModified.After(cutoff), nil
+	}
+}
+
+// HasTag selects objects tagged with `key` set to `value`.
+func HasTag(key, value string) Filter {
+	return func(object ObjectInfo, tags func() (map[string]string, error)) (bool, error) {
+		objectTags, err := tags()
+		if err != nil {
+			return false, err
+		}
+		val, ok := objectTags[key]
+		return ok && val == value, nil
+	}
+}
+
+// All selects objects matching every filter, or every object if there's none.
+func All(filters ...Filter) Filter {
+	return func(object ObjectInfo, tags func() (map[string]string, error)) (bool, error) {
+		for _, filter := range filters {
+			if ok, err := filter(object, tags); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// Any selects objects matching at least one filter.
+func Any(filters ...Filter) Filter {
+	return func(object ObjectInfo, tags func() (map[string]string, error)) (bool, error) {
+		for _, filter := range filters {
+			if ok, err := filter(object, tags); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+}
+
+// Not selects objects not matching `filter`.
+func Not(filter Filter) Filter {
+	return func(object ObjectInfo, tags func() (map[string]string, error)) (bool, error) {
+		ok, err := filter(object, tags)
+		return !ok && err == nil, err
+	}
+}
+
+// WithFilter selects objects listed by `WalkObjects`, downloaded by `DownloadFolder`, removed by `RemoveFiles`
+// and synced by `PlanPush`/`PlanPull`. Multiple filters are combined with `All`.
+func (s Space) WithFilter(filters ...Filter) Space {
+	s.filter = nil
+	if len(filters) > 0 {
+		s.filter = All(filters...)
+	}
+	return s
+}
+
+// selected by `WithFilter`, fetching tags of the object only when needed.
+func (s Space) selected(ctx context.Context, bucketName string, object ObjectInfo) (bool, error) {
+	if s.filter == nil {
+		return true, nil
+	}
+	return s.filter(object, func() (map[string]string, error) {
+		return s.backend.GetObjectTagging(ctx, bucketName, object.Key)
+	})
+}
+
+// selectedFile by `WithFilter` as if it's uploaded as `objectName`, its tags are the ones set by `WithTags`.
+func (s Space) selectedFile(objectName string, fi os.FileInfo) (bool, error) {
+	if s.filter == nil {
+		return true, nil
+	}
+	object := ObjectInfo{Key: objectName, Size: fi.Size(), LastModified: fi.ModTime()}
+	return s.filter(object, func() (map[string]string, error) {
+		return s.tags, nil
+	})
+}
@@ -0,0 +1,170 @@
+package ignore
+
+// Gitignore-style pattern matching used to skip files on recursive push.
+// Supported syntax: `#` comments, `!` negation, trailing `/` for directories only,
+// leading or middle `/` anchoring to the pattern's directory, `*`, `?`, `[...]` and `**`.
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// FileName of ignore files read from every folder on recursive push.
+const FileName = ".spaceignore"
+
+// Defaults ignored on recursive push unless re-included, e.g. with `!.git/` in an ignore file.
+var Defaults = []string{".git/"}
+
+type rule struct {
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Matcher decides whether a path should be ignored. Later rules take precedence, like git.
+type Matcher struct {
+	rules []rule
+}
+
+// New matcher with patterns relative to the root folder.
+func New(patterns ...string) *Matcher {
+	m := &Matcher{}
+	m.Add("", patterns...)
+	return m
+}
+
+// Add patterns relative to `base`, a slash separated folder path from the root.
+func (m *Matcher) Add(base string, patterns ...string) {
+	base = strings.Trim(base, "/")
+	if base == "." {
+		base = ""
+	}
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimRight(pattern, " \t\r")
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		r := rule{base: base}
+		if strings.HasPrefix(pattern, "!") {
+			r.negate = true
+			pattern = pattern[1:]
+		} else if strings.HasPrefix(pattern, `\`) {
+			pattern = pattern[1:]
+		}
+		if strings.HasSuffix(pattern, "/") {
+			r.dirOnly = true
+			pattern = strings.TrimRight(pattern, "/")
+		}
+		if strings.Contains(pattern, "/") {
+			r.anchored = true
+			pattern = strings.TrimPrefix(pattern, "/")
+		}
+		if pattern == "" {
+			continue
+		}
+
+		r.pattern = pattern
+		m.rules = append(m.rules, r)
+	}
+}
+
+// AddFile reads patterns from an ignore file located in `base` folder. Missing file is not an error.
+func (m *Matcher) AddFile(base, filePath string) error {
+	f, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	patterns := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	m.Add(base, patterns...)
+	return nil
+}
+
+// Match reports whether slash separated `name`, relative to the root folder, is ignored.
+// Callers walking a tree should skip ignored directories entirely, as git does.
+func (m *Matcher) Match(name string, isDir bool) bool {
+	ignored, _ := m.Check(name, isDir)
+	return ignored
+}
+
+// Check is like `Match` but also reports whether any rule matched, so matchers can be layered.
+func (m *Matcher) Check(name string, isDir bool) (ignored, matched bool) {
+	if m == nil {
+		return false, false
+	}
+	name = strings.Trim(name, "/")
+
+	for _, r := range m.rules {
+		if r.match(name, isDir) {
+			ignored = !r.negate
+			matched = true
+		}
+	}
+	return ignored, matched
+}
+
+func (r rule) match(name string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	rel := name
+	if r.base != "" {
+		if !strings.HasPrefix(name, r.base+"/") {
+			return false
+		}
+		rel = strings.TrimPrefix(name, r.base+"/")
+	}
+
+	if !r.anchored {
+		return matchGlob(r.pattern, path.Base(rel))
+	}
+	return matchSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments where `**` matches zero or more segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return len(name) > 0
+			}
+			for i := range name {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 || !matchGlob(pattern[0], name[0]) {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
+
+func matchGlob(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
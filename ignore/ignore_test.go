@@ -0,0 +1,66 @@
+package ignore_test
+
+import (
+	"testing"
+
+	"github.com/lebenasa/space/ignore"
+)
+
+func TestMatch(t *testing.T) {
+	m := ignore.New(
+		"# comment",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/root.txt",
+		"docs/**/*.tmp",
+		"vendor/**",
+	)
+	m.Add("sub", "local.txt", "/anchored.txt")
+
+	cases := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"deep/dir/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", true, true},
+		{"root.txt", false, true},
+		{"src/root.txt", false, false},
+		{"docs/a.tmp", false, true},
+		{"docs/a/b/c.tmp", false, true},
+		{"src/docs/a.tmp", false, false},
+		{"vendor", true, false},
+		{"vendor/lib/a.go", false, true},
+		{"sub/local.txt", false, true},
+		{"sub/deep/local.txt", false, true},
+		{"local.txt", false, false},
+		{"sub/anchored.txt", false, true},
+		{"sub/deep/anchored.txt", false, false},
+		{"main.go", false, false},
+	}
+	for i, c := range cases {
+		if got := m.Match(c.name, c.isDir); got != c.want {
+			t.Errorf("case %v %v got %v, want %v", i+1, c.name, got, c.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	m := ignore.New("*.log")
+	if ignored, matched := m.Check("a.txt", false); ignored || matched {
+		t.Errorf("case 1 got %v, %v, want no match", ignored, matched)
+	}
+	if ignored, matched := m.Check("a.log", false); !ignored || !matched {
+		t.Errorf("case 2 got %v, %v, want ignored", ignored, matched)
+	}
+
+	var nilMatcher *ignore.Matcher
+	if nilMatcher.Match("a.log", false) {
+		t.Error("case 3 nil matcher ignores, want no match")
+	}
+}
@@ -0,0 +1,209 @@
+package space
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Multipart upload limits, S3 rejects parts smaller than 5 MiB except the last one.
+const (
+	MinPartSize      = 5 << 20
+	DefaultPartSize  = 64 << 20
+	MaxParts         = 10000
+	BigFileThreshold = 100 << 20
+)
+
+// WithPartSize sets part size in bytes for `UploadBigFile`, at least `MinPartSize`.
+func (s Space) WithPartSize(partSize int64) Space {
+	s.partSize = partSize
+	return s
+}
+
+// partSizeFor a file, grown when needed to stay within `MaxParts`.
+func (s Space) partSizeFor(fileSize int64) int64 {
+	partSize := s.partSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	if partSize < MinPartSize {
+		partSize = MinPartSize
+	}
+	if min := (fileSize + MaxParts - 1) / MaxParts; partSize < min {
+		partSize = (min + MinPartSize - 1) / MinPartSize * MinPartSize
+	}
+	return partSize
+}
+
+// Upload a file into Space, using `UploadBigFile` if it's larger than `BigFileThreshold`.
+func (s Space) Upload(ctx context.Context, fp, env, prefix string) (objectName string, err error) {
+	fi, err := os.Stat(fp)
+	if err != nil {
+		return
+	}
+	if fi.Size() > BigFileThreshold {
+		return s.UploadBigFile(ctx, fp, env, prefix)
+	}
+	return s.UploadFile(ctx, fp, env, prefix)
+}
+
+// UploadBigFile into Space as a multipart upload, parts are uploaded concurrently (see `WithJobs`)
+// and retried individually. If Space is created using `WithTags`, apply those tags into uploaded file.
+// With `WithVerify`, uploaded file is checked against SHA-256 stored in its metadata.
+// With `WithStateDir`, progress is saved so failed upload can be continued using `WithResume`.
+func (s Space) UploadBigFile(ctx context.Context, fp, env, prefix string) (objectName string, err error) {
+	bucket, err := s.Bucket(env)
+	if err != nil {
+		return
+	}
+	objectName = path.Join(prefix, filepath.Base(fp))
+
+	absPath, err := filepath.Abs(fp)
+	if err != nil {
+		return
+	}
+	f, err := os.Open(absPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+
+	options, sum, err := s.uploadOptions(absPath)
+	if err != nil {
+		return
+	}
+
+	state, resumed := s.previousUpload(ctx, bucket, objectName, absPath, fi)
+	if !resumed {
+		state = UploadState{
+			Bucket:    bucket,
+			Object:    objectName,
+			FilePath:  absPath,
+			Size:      fi.Size(),
+			ModTime:   fi.ModTime(),
+			PartSize:  s.partSizeFor(fi.Size()),
+			Initiated: time.Now().UTC(),
+		}
+		state.UploadID, err = s.backend.NewMultipartUpload(ctx, bucket, objectName, options)
+		if err != nil {
+			return
+		}
+	}
+	if err = s.saveUploadState(state); err != nil {
+		return
+	}
+
+	parts, err := s.putParts(ctx, state, f)
+	if err == nil {
+		err = s.backend.CompleteMultipartUpload(ctx, bucket, objectName, state.UploadID, parts)
+	}
+	if err != nil {
+		if s.stateDir == "" {
+			s.backend.AbortMultipartUpload(context.Background(), bucket, objectName, state.UploadID)
+		}
+		return
+	}
+	if err = s.removeUploadState(state); err != nil {
+		return
+	}
+	if s.verify {
+		if err = s.verifyUpload(ctx, bucket, objectName, sum); err != nil {
+			return
+		}
+	}
+
+	if len(s.tags) == 0 {
+		return
+	}
+	err = s.PutTag(ctx, bucket, objectName, s.tags)
+	return
+}
+
+// putParts of a file concurrently, skipping parts already in `state` and saving state after each part.
+// Returns parts sorted by part number.
+func (s Space) putParts(ctx context.Context, state UploadState, file io.ReaderAt) ([]CompletePart, error) {
+	count := int((state.Size + state.PartSize - 1) / state.PartSize)
+	if count == 0 {
+		count = 1
+	}
+
+	jobs := s.jobs
+	if jobs <= 0 {
+		jobs = DefaultJobs
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parts := make([]CompletePart, count)
+	done := make([]bool, count)
+	for _, part := range state.Parts {
+		if part.PartNumber >= 1 && part.PartNumber <= count {
+			parts[part.PartNumber-1] = part
+			done[part.PartNumber-1] = true
+		}
+	}
+
+	mu := sync.Mutex{}
+	failOnce := sync.Once{}
+	var err error
+	sem := make(chan struct{}, jobs)
+	wg := sync.WaitGroup{}
+	for i := 0; i < count; i++ {
+		if done[i] {
+			continue
+		}
+		offset := int64(i) * state.PartSize
+		size := state.PartSize
+		if offset+size > state.Size {
+			size = state.Size - offset
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, offset, size int64) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			part, pErr := s.putPart(ctx, state.Bucket, state.Object, state.UploadID, i+1, io.NewSectionReader(file, offset, size), size)
+			if pErr == nil {
+				mu.Lock()
+				parts[i] = part
+				state.Parts = append(state.Parts, part)
+				pErr = s.saveUploadState(state)
+				mu.Unlock()
+			}
+			if pErr != nil {
+				failOnce.Do(func() {
+					err = fmt.Errorf("Failed to upload part %v of %v: %w", i+1, state.Object, pErr)
+					cancel()
+				})
+			}
+		}(i, offset, size)
+	}
+	wg.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// putPart from a section of the file, retried by `WithRetry` policy.
+func (s Space) putPart(ctx context.Context, bucket, objectName, uploadID string, partNumber int, reader io.ReadSeeker, size int64) (part CompletePart, err error) {
+	uploaded, err := s.backend.PutObjectPart(ctx, bucket, objectName, uploadID, partNumber, reader, size)
+	if err != nil {
+		return part, err
+	}
+	return CompletePart{PartNumber: uploaded.PartNumber, ETag: uploaded.ETag}, nil
+}
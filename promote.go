@@ -0,0 +1,71 @@
+package space
+
+// Promoting the same bytes from one environment to another, e.g. once tested on dev, without downloading them.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+)
+
+// User metadata keys recording who promoted an object, from which environment and when, see `Promote`.
+const (
+	PromotedByMetadata   = "Promoted-By"
+	PromotedFromMetadata = "Promoted-From"
+	PromotedAtMetadata   = "Promoted-At"
+)
+
+// Promote every object under `prefix`, treated as a folder, selected by `WithFilter` from an environment to another,
+// see `PromoteObjects`.
+func (s Space) Promote(ctx context.Context, prefix, fromEnv, toEnv string) (objectNames []string, err error) {
+	planned := []string{}
+	err = s.Walk(ctx, fromEnv, ListOptions{Prefix: folderPrefix(prefix), Recursive: true}, func(object ObjectInfo) error {
+		planned = append(planned, object.Key)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	return s.PromoteObjects(ctx, fromEnv, toEnv, planned)
+}
+
+// PromoteObjects from an environment to another concurrently with server side copies, see `Copy` and `WithJobs`.
+// Promoted objects keep their metadata and tags, and record who promoted them in their user metadata.
+// Failed objects don't stop the promotion, they're reported with `WithReport` and returned as `*TransferError`,
+// where `TransferResult.Path` is the source object as "{bucket}/{object}".
+func (s Space) PromoteObjects(ctx context.Context, fromEnv, toEnv string, objectNames []string) (promoted []string, err error) {
+	srcBucket, err := s.Bucket(fromEnv)
+	if err != nil {
+		return
+	}
+	dstBucket, err := s.Bucket(toEnv)
+	if err != nil {
+		return
+	}
+	if srcBucket == dstBucket {
+		return nil, fmt.Errorf("Invalid promotion, %v and %v use the same bucket %v", fromEnv, toEnv, srcBucket)
+	}
+
+	metadata := map[string]string{
+		PromotedByMetadata:   promoter(),
+		PromotedFromMetadata: fromEnv,
+		PromotedAtMetadata:   time.Now().UTC().Format(time.RFC3339),
+	}
+
+	return s.copyObjects(ctx, srcBucket, objectNames, objectNames, func(srcObject, dstObject string) error {
+		return s.Copy(ctx, srcBucket, srcObject, dstBucket, dstObject, metadata)
+	})
+}
+
+// promoter is the name of current user, "unknown" if it can't be found.
+func promoter() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
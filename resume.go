@@ -0,0 +1,186 @@
+package space
+
+// Local state of multipart uploads so interrupted `UploadBigFile` can be resumed.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UploadState of a multipart upload, saved after every uploaded part.
+type UploadState struct {
+	Bucket    string         `json:"bucket"`
+	Object    string         `json:"object"`
+	UploadID  string         `json:"upload_id"`
+	FilePath  string         `json:"file_path"`
+	Size      int64          `json:"size"`
+	ModTime   time.Time      `json:"mod_time"`
+	PartSize  int64          `json:"part_size"`
+	Parts     []CompletePart `json:"parts"`
+	Initiated time.Time      `json:"initiated"`
+}
+
+// DefaultStateDir for upload states, `space/uploads` under user's cache dir.
+func DefaultStateDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "space", "uploads"), nil
+}
+
+// WithStateDir saves `UploadBigFile` progress in `dir`, keeping failed uploads so they can be resumed.
+func (s Space) WithStateDir(dir string) Space {
+	s.stateDir = dir
+	return s
+}
+
+// WithResume continues `UploadBigFile` from its saved state, see `WithStateDir`.
+// Otherwise previous incomplete upload of the same file is aborted and started over.
+func (s Space) WithResume(resume bool) Space {
+	s.resume = resume
+	return s
+}
+
+// LoadUploadStates saved in `dir`, sorted by object name.
+func LoadUploadStates(dir string) (states []UploadState, err error) {
+	fis, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range fis {
+		if !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+		state, err := readUploadState(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Object < states[j].Object
+	})
+	return states, nil
+}
+
+func readUploadState(fp string) (state UploadState, err error) {
+	data, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// statePath is unique for each bucket, object and local file.
+func (s Space) statePath(bucket, objectName, filePath string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{bucket, objectName, filePath}, "\x00")))
+	return filepath.Join(s.stateDir, hex.EncodeToString(sum[:16])+".json")
+}
+
+func (s Space) saveUploadState(state UploadState) error {
+	if s.stateDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(s.stateDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fp := s.statePath(state.Bucket, state.Object, state.FilePath)
+	tmp := fp + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fp)
+}
+
+func (s Space) removeUploadState(state UploadState) error {
+	if s.stateDir == "" {
+		return nil
+	}
+	err := os.Remove(s.statePath(state.Bucket, state.Object, state.FilePath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// previousUpload of the same file, with parts already in Space. Returns false if there's nothing to resume.
+// Unless resuming, previous upload is aborted.
+func (s Space) previousUpload(ctx context.Context, bucket, objectName, filePath string, fi os.FileInfo) (state UploadState, ok bool) {
+	if s.stateDir == "" {
+		return state, false
+	}
+	state, err := readUploadState(s.statePath(bucket, objectName, filePath))
+	if err != nil {
+		return state, false
+	}
+
+	unchanged := state.Size == fi.Size() && state.ModTime.Equal(fi.ModTime())
+	if !s.resume || !unchanged {
+		s.backend.AbortMultipartUpload(ctx, bucket, objectName, state.UploadID)
+		s.removeUploadState(state)
+		return state, false
+	}
+
+	parts, err := s.backend.ListObjectParts(ctx, bucket, objectName, state.UploadID)
+	if err != nil {
+		s.removeUploadState(state)
+		return state, false
+	}
+	state.Parts = state.Parts[:0]
+	for _, part := range parts {
+		state.Parts = append(state.Parts, CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	return state, true
+}
+
+// IncompleteUploads in an environment with given object prefix.
+func (s Space) IncompleteUploads(ctx context.Context, env, prefix string) ([]ObjectMultipartInfo, error) {
+	bucket, err := s.Bucket(env)
+	if err != nil {
+		return nil, err
+	}
+	return s.backend.ListMultipartUploads(ctx, bucket, prefix)
+}
+
+// AbortUpload of an object in an environment, removing its local state from `WithStateDir` if any.
+func (s Space) AbortUpload(ctx context.Context, env, objectName, uploadID string) error {
+	bucket, err := s.Bucket(env)
+	if err != nil {
+		return err
+	}
+	if err = s.backend.AbortMultipartUpload(ctx, bucket, objectName, uploadID); err != nil {
+		return err
+	}
+
+	if s.stateDir == "" {
+		return nil
+	}
+	states, err := LoadUploadStates(s.stateDir)
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		if state.UploadID == uploadID {
+			return s.removeUploadState(state)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+package space
+
+// Retrying failed operations with exponential backoff.
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// RetryPolicy decides whether and when failed operations are tried again, see `WithRetry`.
+type RetryPolicy struct {
+	// MaxAttempts including the first one, retries are disabled if less than 2.
+	MaxAttempts int
+	// BaseDelay before the first retry, doubled after every attempt up to `MaxDelay`.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter randomizes a fraction of each delay, from 0 to 1, so concurrent transfers don't retry at once.
+	Jitter float64
+	// Retryable tells whether an error is worth retrying, `IsRetryable` if nil.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy of Space unless set with `WithRetry`.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.2,
+}
+
+// NoRetry fails on the first error.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// retryableCodes of S3 error responses that may succeed later, despite their 4xx status.
+var retryableCodes = map[string]bool{
+	"RequestTimeout":       true,
+	"RequestTimeTooSkewed": true,
+	"SlowDown":             true,
+	"Throttling":           true,
+	"InternalError":        true,
+	"ServiceUnavailable":   true,
+}
+
+// IsRetryable unless the error is permanent: a cancelled or expired context, a known error kind like `ErrNotFound`,
+// a local file error, or an S3 error response other than server errors (except not implemented), throttling and timeouts.
+// Anything else, e.g. network failures, is retried.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	for _, kind := range []error{ErrNotFound, ErrAccessDenied, ErrBucketNotFound, ErrInvalidEnv, ErrConflict} {
+		if errors.Is(err, kind) {
+			return false
+		}
+	}
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return false
+	}
+	var resp minio.ErrorResponse
+	if errors.As(err, &resp) {
+		if resp.StatusCode == http.StatusNotImplemented {
+			return false
+		}
+		return retryableCodes[resp.Code] || resp.StatusCode >= http.StatusInternalServerError ||
+			resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	}
+	return true
+}
+
+var disableClientRetries sync.Once
+
+// DisableClientRetries of every minio client in the process, so `RetryPolicy` is the only retry layer.
+// minio-go retries each request up to `minio.MaxRetry` times on its own, and has no per-client setting,
+// so call it once at startup, before any request, e.g. when using `New` or `NewFromClient` in a command.
+func DisableClientRetries() {
+	disableClientRetries.Do(func() {
+		minio.MaxRetry = 1
+	})
+}
+
+// WithRetry sets how every operation of Space is retried, e.g. `NoRetry`.
+// Clients from `NewFromProfile` or `NewFromClient` also retry requests themselves, see `DisableClientRetries`.
+func (s Space) WithRetry(policy RetryPolicy) Space {
+	if b, ok := s.backend.(retryBackend); ok {
+		s.backend = b.backend
+	}
+	s.backend = retryBackend{backend: s.backend, policy: policy}
+	return s
+}
+
+// Do calls `fn` until it succeeds, fails with an error not worth retrying, attempts run out or `ctx` is done.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) (err error) {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= p.MaxAttempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(p.delay(attempt)):
+		}
+	}
+}
+
+// delay after given failed attempt, with jitter.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// rewinder of a reader to where it started, so uploads can be retried. Readers which can't seek are only tried once.
+func rewinder(reader io.Reader) (rewind func() error, ok bool) {
+	seeker, ok := reader.(io.Seeker)
+	if !ok {
+		return nil, false
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, false
+	}
+	return func() error {
+		_, err := seeker.Seek(start, io.SeekStart)
+		return err
+	}, true
+}
+
+// retryBackend retries every operation of another backend with a `RetryPolicy`.
+type retryBackend struct {
+	backend Backend
+	policy  RetryPolicy
+}
+
+func (b retryBackend) SetAppInfo(appName, appVersion string) {
+	if backend, ok := b.backend.(interface{ SetAppInfo(string, string) }); ok {
+		backend.SetAppInfo(appName, appVersion)
+	}
+}
+
+func (b retryBackend) ListBuckets(ctx context.Context) (buckets []BucketInfo, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		buckets, err = b.backend.ListBuckets(ctx)
+		return
+	})
+	return
+}
+
+func (b retryBackend) ListObjects(ctx context.Context, bucketName, objectPrefix string, recursive bool) (objects []ObjectInfo, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		objects, err = b.backend.ListObjects(ctx, bucketName, objectPrefix, recursive)
+		return
+	})
+	return
+}
+
+func (b retryBackend) ListObjectsPage(ctx context.Context, bucketName string, options ListOptions, token string) (objects []ObjectInfo, next string, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		objects, next, err = b.backend.ListObjectsPage(ctx, bucketName, options, token)
+		return
+	})
+	return
+}
+
+func (b retryBackend) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, options PutObjectOptions) (n int64, err error) {
+	rewind, ok := rewinder(reader)
+	if !ok {
+		return b.backend.PutObject(ctx, bucketName, objectName, reader, objectSize, options)
+	}
+	attempted := false
+	err = b.policy.Do(ctx, func() (err error) {
+		if attempted {
+			if err = rewind(); err != nil {
+				return err
+			}
+		}
+		attempted = true
+		n, err = b.backend.PutObject(ctx, bucketName, objectName, reader, objectSize, options)
+		return
+	})
+	return
+}
+
+func (b retryBackend) GetObject(ctx context.Context, bucketName, objectName string, options GetObjectOptions) (object Object, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		object, err = b.backend.GetObject(ctx, bucketName, objectName, options)
+		return
+	})
+	return
+}
+
+func (b retryBackend) StatObject(ctx context.Context, bucketName, objectName string, options StatObjectOptions) (info ObjectInfo, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		info, err = b.backend.StatObject(ctx, bucketName, objectName, options)
+		return
+	})
+	return
+}
+
+func (b retryBackend) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	return b.policy.Do(ctx, func() error {
+		return b.backend.RemoveObject(ctx, bucketName, objectName)
+	})
+}
+
+// RemoveObjects retrying only objects which failed with a retryable error.
+func (b retryBackend) RemoveObjects(ctx context.Context, bucketName string, objectNames []string) []RemoveObjectError {
+	retryable := b.policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	var permanent, last []RemoveObjectError
+	b.policy.Do(ctx, func() (err error) {
+		failed := b.backend.RemoveObjects(ctx, bucketName, objectNames)
+		last, objectNames = nil, nil
+		for _, rErr := range failed {
+			if !retryable(rErr.Err) {
+				permanent = append(permanent, rErr)
+				continue
+			}
+			last = append(last, rErr)
+			objectNames = append(objectNames, rErr.ObjectName)
+			err = rErr.Err
+		}
+		return
+	})
+	return append(permanent, last...)
+}
+
+func (b retryBackend) PutObjectTagging(ctx context.Context, bucketName, objectName string, tags map[string]string) error {
+	return b.policy.Do(ctx, func() error {
+		return b.backend.PutObjectTagging(ctx, bucketName, objectName, tags)
+	})
+}
+
+func (b retryBackend) GetObjectTagging(ctx context.Context, bucketName, objectName string) (tags map[string]string, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		tags, err = b.backend.GetObjectTagging(ctx, bucketName, objectName)
+		return
+	})
+	return
+}
+
+func (b retryBackend) RemoveObjectTagging(ctx context.Context, bucketName, objectName string) error {
+	return b.policy.Do(ctx, func() error {
+		return b.backend.RemoveObjectTagging(ctx, bucketName, objectName)
+	})
+}
+
+func (b retryBackend) CopyObject(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string, options PutObjectOptions) error {
+	return b.policy.Do(ctx, func() error {
+		return b.backend.CopyObject(ctx, srcBucket, srcObject, dstBucket, dstObject, options)
+	})
+}
+
+func (b retryBackend) NewMultipartUpload(ctx context.Context, bucketName, objectName string, options PutObjectOptions) (uploadID string, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		uploadID, err = b.backend.NewMultipartUpload(ctx, bucketName, objectName, options)
+		return
+	})
+	return
+}
+
+func (b retryBackend) PutObjectPart(ctx context.Context, bucketName, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (part ObjectPart, err error) {
+	rewind, ok := rewinder(reader)
+	if !ok {
+		return b.backend.PutObjectPart(ctx, bucketName, objectName, uploadID, partNumber, reader, partSize)
+	}
+	attempted := false
+	err = b.policy.Do(ctx, func() (err error) {
+		if attempted {
+			if err = rewind(); err != nil {
+				return err
+			}
+		}
+		attempted = true
+		part, err = b.backend.PutObjectPart(ctx, bucketName, objectName, uploadID, partNumber, reader, partSize)
+		return
+	})
+	return
+}
+
+func (b retryBackend) CompleteMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string, parts []CompletePart) error {
+	return b.policy.Do(ctx, func() error {
+		return b.backend.CompleteMultipartUpload(ctx, bucketName, objectName, uploadID, parts)
+	})
+}
+
+func (b retryBackend) AbortMultipartUpload(ctx context.Context, bucketName, objectName, uploadID string) error {
+	return b.policy.Do(ctx, func() error {
+		return b.backend.AbortMultipartUpload(ctx, bucketName, objectName, uploadID)
+	})
+}
+
+func (b retryBackend) ListMultipartUploads(ctx context.Context, bucketName, objectPrefix string) (uploads []ObjectMultipartInfo, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		uploads, err = b.backend.ListMultipartUploads(ctx, bucketName, objectPrefix)
+		return
+	})
+	return
+}
+
+func (b retryBackend) ListObjectParts(ctx context.Context, bucketName, objectName, uploadID string) (parts []ObjectPart, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		parts, err = b.backend.ListObjectParts(ctx, bucketName, objectName, uploadID)
+		return
+	})
+	return
+}
+
+func (b retryBackend) CopyObjectPart(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject, uploadID string, partNumber int, offset, length int64) (part CompletePart, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		part, err = b.backend.CopyObjectPart(ctx, srcBucket, srcObject, dstBucket, dstObject, uploadID, partNumber, offset, length)
+		return
+	})
+	return
+}
+
+func (b retryBackend) PresignGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (u *url.URL, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		u, err = b.backend.PresignGetObject(ctx, bucketName, objectName, expires)
+		return
+	})
+	return
+}
+
+func (b retryBackend) PresignPutObject(ctx context.Context, bucketName, objectName string, expires time.Duration) (u *url.URL, err error) {
+	err = b.policy.Do(ctx, func() (err error) {
+		u, err = b.backend.PresignPutObject(ctx, bucketName, objectName, expires)
+		return
+	})
+	return
+}
@@ -0,0 +1,38 @@
+package service
+
+import "fmt"
+
+// Compiled-in fallback values. Empty unless `env.go` is generated from `env.go.template`.
+
+// SpaceEndpoint to the object store, e.g. s3.aws.com or ny1.digitaloceanspaces.com.
+var SpaceEndpoint = ""
+
+// SpaceKey is an access key to the object store.
+var SpaceKey = ""
+
+// SpaceSecret a secret hash to access the object store.
+var SpaceSecret = ""
+
+// Environments in Space to work with. Maps to bucket name.
+var environments = map[string]string{}
+
+// Environments returns a copy of compiled-in environment to bucket map.
+func Environments() map[string]string {
+	envs := make(map[string]string, len(environments))
+	for key, bucket := range environments {
+		envs[key] = bucket
+	}
+	return envs
+}
+
+// GetBucket name from given environment name.
+func GetBucket(env string) (string, error) {
+	envs := make([]string, 0, len(environments))
+	for key, bucket := range environments {
+		envs = append(envs, key)
+		if key == env {
+			return bucket, nil
+		}
+	}
+	return "", fmt.Errorf("Invalid environment %v, possible values: %v", env, envs)
+}
@@ -0,0 +1,72 @@
+package space
+
+// Presigned URLs, sharing objects without credentials for a limited time.
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lebenasa/space/config"
+)
+
+// SharedURL of an object, valid until it expires.
+type SharedURL struct {
+	URL     string
+	Method  string
+	Expires time.Time
+}
+
+// PresignGet returns a URL to download an object without credentials, valid for `expires` up to `config.MaxShareExpiry`.
+func (s Space) PresignGet(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error) {
+	if err := checkExpiry(expires, config.MaxShareExpiry); err != nil {
+		return nil, err
+	}
+	return s.backend.PresignGetObject(ctx, bucketName, objectName, expires)
+}
+
+// PresignPut returns a URL to upload an object without credentials, valid for `expires` up to `config.MaxShareExpiry`.
+func (s Space) PresignPut(ctx context.Context, bucketName, objectName string, expires time.Duration) (*url.URL, error) {
+	if err := checkExpiry(expires, config.MaxShareExpiry); err != nil {
+		return nil, err
+	}
+	return s.backend.PresignPutObject(ctx, bucketName, objectName, expires)
+}
+
+// Share an object of an environment with a presigned URL to download it, or to upload it if `upload`.
+// Expiry is limited per environment, see `config.Profile.ShareExpiry`.
+func (s Space) Share(ctx context.Context, env, objectName string, expires time.Duration, upload bool) (shared SharedURL, err error) {
+	bucket, err := s.Bucket(env)
+	if err != nil {
+		return
+	}
+	max, err := s.cfg.ShareExpiry(env)
+	if err != nil {
+		return
+	}
+	if expires > max {
+		return shared, fmt.Errorf("Invalid expiry %v, sharing from %v is limited to %v", expires, env, max)
+	}
+
+	shared.Method, shared.Expires = http.MethodGet, time.Now().UTC().Add(expires).Truncate(time.Second)
+	presign := s.PresignGet
+	if upload {
+		shared.Method, presign = http.MethodPut, s.PresignPut
+	}
+	u, err := presign(ctx, bucket, objectName, expires)
+	if err != nil {
+		return SharedURL{}, err
+	}
+	shared.URL = u.String()
+	return shared, nil
+}
+
+// checkExpiry of a presigned URL, at least a second and at most `max`.
+func checkExpiry(expires, max time.Duration) error {
+	if expires < time.Second || expires > max {
+		return fmt.Errorf("Invalid expiry %v, want from 1s to %v", expires, max)
+	}
+	return nil
+}
@@ -4,20 +4,38 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/lebenasa/space/service"
+	"github.com/lebenasa/space/config"
+	"github.com/lebenasa/space/ignore"
 	"github.com/minio/minio-go/v6"
 )
 
+// Endpoints served by non-S3 backends, see `NewFromProfile`.
+const (
+	MemoryEndpoint      = "mem://"
+	LocalEndpointPrefix = "file://"
+)
+
 // Space access client to limit what can be done programatically to our Spaces.
 type Space struct {
-	client *minio.Client
-	tags   map[string]string
+	backend     Backend
+	cfg         config.Profile
+	tags        map[string]string
+	jobs        int
+	partSize    int64
+	stateDir    string
+	resume      bool
+	verify      bool
+	deleteExtra bool
+	report      func(TransferResult)
+	matcher     *ignore.Matcher
+	filter      Filter
 }
 
-// Object represents an open object.
-type Object = minio.Object
-
 // BucketInfo contains bucket's metadata.
 type BucketInfo = minio.BucketInfo
 
@@ -33,109 +51,207 @@ type GetObjectOptions = minio.GetObjectOptions
 // StatObjectOptions specifies additional headers when stating object in Space.
 type StatObjectOptions = minio.StatObjectOptions
 
-// New space client.
-// Requires generated `service` module that's not tracked by git.
-func New() (space Space, err error) {
-	client, err := minio.New(service.SpaceEndpoint, service.SpaceKey, service.SpaceSecret, true)
+// New space client from runtime configuration with default profile, see `config.Load`.
+// Options like `WithInsecure` override connection settings of the profile.
+func New(options ...Option) (space Space, err error) {
+	return NewWithProfile("", options...)
+}
+
+// NewWithProfile creates space client using named profile from runtime configuration.
+func NewWithProfile(name string, options ...Option) (space Space, err error) {
+	cfg, err := config.Load("")
+	if err != nil {
+		return space, err
+	}
+	profile, err := cfg.Select(name)
 	if err != nil {
 		return space, err
 	}
+	return NewFromProfile(profile, options...)
+}
+
+// NewFromProfile creates space client with given endpoint, credentials, connection settings and environments,
+// overridden by `options`. Endpoint `MemoryEndpoint` keeps objects in memory,
+// while "file://{dir}" stores them in a local directory.
+func NewFromProfile(profile config.Profile, options ...Option) (space Space, err error) {
+	o := settings{profile: profile}
+	for _, option := range options {
+		option(&o)
+	}
+	profile = o.profile
 
-	space.client = client
+	buckets := make([]string, 0, len(profile.Environments))
+	for _, bucket := range profile.Environments {
+		buckets = append(buckets, bucket)
+	}
+
+	switch {
+	case profile.Endpoint == MemoryEndpoint:
+		space.backend = NewMemoryBackend(buckets...)
+	case strings.HasPrefix(profile.Endpoint, LocalEndpointPrefix):
+		space.backend, err = NewLocalBackend(strings.TrimPrefix(profile.Endpoint, LocalEndpointPrefix), buckets...)
+	default:
+		var client *minio.Client
+		client, err = newMinioClient(o)
+		space.backend = NewMinioBackend(client)
+	}
+	if err != nil {
+		return Space{}, err
+	}
+
+	space.backend = typed(space.backend)
+	space.cfg = profile
+	space = space.WithRetry(DefaultRetryPolicy)
 	return
 }
 
-// NewFromClient via `minio.New`.
+// NewFromClient via `minio.New`. Environments fallback to compiled-in `service` values.
+// The client keeps retrying requests on its own unless `DisableClientRetries` is called.
 func NewFromClient(client *minio.Client) (space Space) {
-	space.client = client
-	return
+	return NewFromBackend(NewMinioBackend(client))
 }
 
-// SetAppInfo adds custom application details to User-Agent.
-func (s Space) SetAppInfo(appName, appVersion string) {
-	s.client.SetAppInfo(appName, appVersion)
+// NewFromBackend such as `NewMemoryBackend` or `NewLocalBackend`.
+// Environments fallback to compiled-in `service` values.
+func NewFromBackend(backend Backend) (space Space) {
+	space.backend = typed(backend)
+	space.cfg = config.Default().Profile
+	return space.WithRetry(DefaultRetryPolicy)
 }
 
-// ListBuckets in current endpoint.
-func (s Space) ListBuckets() ([]BucketInfo, error) {
-	return s.client.ListBuckets()
+// WithEnvironments that map environment name to bucket name, used by task functions.
+func (s Space) WithEnvironments(environments map[string]string) Space {
+	s.cfg.Environments = environments
+	return s
 }
 
-// ListObjects inside a bucket.
-func (s Space) ListObjects(bucketName string, objectPrefix string, recursive bool) (objects []ObjectInfo, err error) {
-	doneCh := make(chan struct{})
-	defer close(doneCh)
+// Bucket name of given environment, unknown environment is `ErrInvalidEnv`.
+func (s Space) Bucket(env string) (string, error) {
+	bucket, err := s.cfg.Bucket(env)
+	if err != nil {
+		return "", &Error{Kind: ErrInvalidEnv, Err: err}
+	}
+	return bucket, nil
+}
 
-	objectCh := s.client.ListObjectsV2(bucketName, objectPrefix, recursive, doneCh)
-	for object := range objectCh {
-		if object.Err != nil {
-			return nil, object.Err
-		}
-		objects = append(objects, object)
+// SetAppInfo adds custom application details to User-Agent, if backend supports it.
+func (s Space) SetAppInfo(appName, appVersion string) {
+	if b, ok := s.backend.(interface{ SetAppInfo(string, string) }); ok {
+		b.SetAppInfo(appName, appVersion)
 	}
+}
+
+// ListBuckets in current endpoint.
+func (s Space) ListBuckets(ctx context.Context) ([]BucketInfo, error) {
+	return s.backend.ListBuckets(ctx)
+}
 
-	return objects, err
+// ListObjects inside a bucket.
+func (s Space) ListObjects(ctx context.Context, bucketName string, objectPrefix string, recursive bool) (objects []ObjectInfo, err error) {
+	return s.backend.ListObjects(ctx, bucketName, objectPrefix, recursive)
 }
 
 // Put object to Space.
 func (s Space) Put(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, options PutObjectOptions) (int64, error) {
-	return s.client.PutObjectWithContext(ctx, bucketName, objectName, reader, objectSize, options)
+	return s.backend.PutObject(ctx, bucketName, objectName, reader, objectSize, options)
 }
 
 // Get object from Space.
-func (s Space) Get(ctx context.Context, bucketName, objectName string, options GetObjectOptions) (*Object, error) {
-	return s.client.GetObjectWithContext(ctx, bucketName, objectName, options)
+func (s Space) Get(ctx context.Context, bucketName, objectName string, options GetObjectOptions) (Object, error) {
+	return s.backend.GetObject(ctx, bucketName, objectName, options)
 }
 
 // PutFile to Space (upload a file).
 func (s Space) PutFile(ctx context.Context, bucketName, objectName, filePath string, options PutObjectOptions) (length int64, err error) {
-	return s.client.FPutObjectWithContext(ctx, bucketName, objectName, filePath, options)
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if fi.IsDir() {
+		return 0, fmt.Errorf("%v is a directory", filePath)
+	}
+
+	return s.Put(ctx, bucketName, objectName, f, fi.Size(), options)
 }
 
-// GetFile from Space (download a file).
-func (s Space) GetFile(ctx context.Context, bucketName, objectName, filePath string, options GetObjectOptions) error {
-	return s.client.FGetObjectWithContext(ctx, bucketName, objectName, filePath, options)
+// GetFile from Space (download a file). The file is only replaced once fully downloaded.
+func (s Space) GetFile(ctx context.Context, bucketName, objectName, filePath string, options GetObjectOptions) (err error) {
+	object, err := s.Get(ctx, bucketName, objectName, options)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	dir := filepath.Dir(filePath)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(filePath)+".part-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, object)
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
 }
 
 // Stat of an object in Space.
-func (s Space) Stat(bucketName, objectName string, options StatObjectOptions) (ObjectInfo, error) {
-	return s.client.StatObject(bucketName, objectName, options)
+func (s Space) Stat(ctx context.Context, bucketName, objectName string, options StatObjectOptions) (ObjectInfo, error) {
+	return s.backend.StatObject(ctx, bucketName, objectName, options)
 }
 
 // Remove object in Space.
-func (s Space) Remove(bucketName, objectName string) error {
-	return s.client.RemoveObject(bucketName, objectName)
+func (s Space) Remove(ctx context.Context, bucketName, objectName string) error {
+	return s.backend.RemoveObject(ctx, bucketName, objectName)
 }
 
-// RemoveObjects in Space.
-func (s Space) RemoveObjects(ctx context.Context, bucketName string, objectNames []string) (err error) {
-	objectsCh := make(chan string)
-
-	go func() {
-		defer close(objectsCh)
-		for _, name := range objectNames {
-			objectsCh <- name
-		}
-	}()
-
-	for rErr := range s.client.RemoveObjectsWithContext(ctx, bucketName, objectsCh) {
-		err = fmt.Errorf("%v\nFailed to remove %v: %v", err, rErr.ObjectName, rErr)
+// RemoveObjects in Space. Objects failed to be removed are returned as `*BatchError`.
+func (s Space) RemoveObjects(ctx context.Context, bucketName string, objectNames []string) error {
+	rErrs := s.backend.RemoveObjects(ctx, bucketName, objectNames)
+	if len(rErrs) == 0 {
+		return nil
 	}
 
-	return err
+	bErr := &BatchError{Op: "remove", Total: len(objectNames)}
+	for _, rErr := range rErrs {
+		bErr.Failed = append(bErr.Failed, newObjectError(rErr.ObjectName, rErr.Err))
+	}
+	return bErr
 }
 
 // PutTag on an object in Space.
 func (s Space) PutTag(ctx context.Context, bucketName, objectName string, tags map[string]string) error {
-	return s.client.PutObjectTaggingWithContext(ctx, bucketName, objectName, tags)
+	return s.backend.PutObjectTagging(ctx, bucketName, objectName, tags)
 }
 
 // GetTag of an object in Space. Returned string is in XML format.
 func (s Space) GetTag(ctx context.Context, bucketName, objectName string) (string, error) {
-	return s.client.GetObjectTaggingWithContext(ctx, bucketName, objectName)
+	tags, err := s.GetTags(ctx, bucketName, objectName)
+	if err != nil {
+		return "", err
+	}
+	return marshalTags(tags)
+}
+
+// GetTags of an object in Space.
+func (s Space) GetTags(ctx context.Context, bucketName, objectName string) (map[string]string, error) {
+	return s.backend.GetObjectTagging(ctx, bucketName, objectName)
 }
 
 // RemoveTag from an object in Space.
 func (s Space) RemoveTag(ctx context.Context, bucketName, objectName string) error {
-	return s.client.RemoveObjectTaggingWithContext(ctx, bucketName, objectName)
+	return s.backend.RemoveObjectTagging(ctx, bucketName, objectName)
 }
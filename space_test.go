@@ -2,39 +2,146 @@ package space_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/lebenasa/space"
-	"github.com/lebenasa/space/service"
+	"github.com/lebenasa/space/config"
 	"github.com/minio/minio-go/v6"
 )
 
+// TestMain runs every test against in-memory backend, ignoring user's config file.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "space-test")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	cfgPath := filepath.Join(dir, "config.json")
+	ioutil.WriteFile(cfgPath, []byte("{}"), 0600)
+
+	os.Setenv(config.EnvConfig, cfgPath)
+	os.Setenv(config.EnvEndpoint, space.MemoryEndpoint)
+	os.Setenv(config.EnvEnvironments, "dev=dev.bucket,live=live.bucket")
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestNew(t *testing.T) {
 	_, err := space.New()
 	if err != nil {
 		t.Errorf("case 1 got %v, want nil error", err)
 	}
 
-	endpoint := service.SpaceEndpoint
-	service.SpaceEndpoint = "https://foo.bar.com"
+	os.Setenv(config.EnvEndpoint, "https://foo.bar.com")
 	_, err = space.New()
-	service.SpaceEndpoint = endpoint
+	os.Setenv(config.EnvEndpoint, space.MemoryEndpoint)
 	if err == nil {
 		t.Errorf("case 2 got %v, want error", err)
 	}
 }
 
+func TestNewWithOptions(t *testing.T) {
+	profile := config.Profile{Endpoint: "localhost:9000", Key: "key", Secret: "secret"}
+	if _, err := space.NewFromProfile(profile); err != nil {
+		t.Errorf("case 1 got %v, want nil error", err)
+	}
+
+	os.Setenv(config.EnvEndpoint, "localhost:9000")
+	_, err := space.New(space.WithInsecure(true), space.WithAddressing(config.AddressingPath), space.WithRegion("us-east-1"))
+	if err != nil {
+		t.Errorf("case 2 got %v, want nil error", err)
+	}
+	_, err = space.New(space.WithAddressing("subdomain"))
+	if err == nil {
+		t.Error("case 3 got no error, want invalid addressing")
+	}
+	_, err = space.New(space.WithCACert("missing.pem"))
+	if err == nil {
+		t.Error("case 4 got no error, want missing CA certificate")
+	}
+	_, err = space.New(space.WithClientCert("client.pem", ""))
+	os.Setenv(config.EnvEndpoint, space.MemoryEndpoint)
+	if err == nil {
+		t.Error("case 5 got no error, want missing client key")
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "space-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	credentialsFile := filepath.Join(dir, "credentials")
+	ioutil.WriteFile(credentialsFile, []byte("[work]\naws_access_key_id = file-key\naws_secret_access_key = file-secret\n"), 0600)
+
+	file := &space.FileProvider{Filename: credentialsFile, Profile: "work"}
+	if value, err := file.Retrieve(); err != nil || value.AccessKeyID != "file-key" || file.IsExpired() {
+		t.Errorf("case 1 got %+v, %v, want file-key", value, err)
+	}
+
+	expiration := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	helper := &space.HelperProvider{Command: `echo '{"Version": 1, "AccessKeyId": "helper-key", "SecretAccessKey": "helper-secret", "Expiration": "` + expiration + `"}'`}
+	if value, err := helper.Retrieve(); err != nil || value.AccessKeyID != "helper-key" || helper.IsExpired() {
+		t.Errorf("case 2 got %+v, %v, want helper-key", value, err)
+	}
+	helper.ExpiryWindow = 2 * time.Hour
+	if _, err := helper.Retrieve(); err != nil || !helper.IsExpired() {
+		t.Errorf("case 3 got %v, want credentials expiring within window", err)
+	}
+
+	static := &space.ChainProvider{Providers: []space.CredentialProvider{
+		&space.HelperProvider{Command: "exit 1"},
+		&space.FileProvider{Filename: filepath.Join(dir, "missing")},
+		file,
+	}}
+	if value, err := static.Retrieve(); err != nil || value.AccessKeyID != "file-key" {
+		t.Errorf("case 4 got %+v, %v, want failing helper skipped", value, err)
+	}
+	failing := &space.ChainProvider{Providers: []space.CredentialProvider{&space.HelperProvider{Command: "echo '{}'"}}}
+	if _, err := failing.Retrieve(); !errors.Is(err, space.ErrAccessDenied) || !failing.IsExpired() {
+		t.Errorf("case 5 got %v, want ErrAccessDenied", err)
+	}
+
+	profile := config.Profile{Endpoint: "localhost:9000", Credentials: []string{"keychain"}}
+	if _, err = space.NewFromProfile(profile); err == nil {
+		t.Error("case 6 got no error, want invalid credential source")
+	}
+	if _, err = space.NewFromProfile(profile, space.WithCredentials(file)); err != nil {
+		t.Errorf("case 7 got %v, want nil error", err)
+	}
+}
+
 func TestNewFromClient(t *testing.T) {
-	client, _ := minio.New(service.SpaceEndpoint, service.SpaceKey, service.SpaceSecret, true)
+	client, _ := minio.New("play.min.io", "key", "secret", true)
 	s := space.NewFromClient(client)
 	s.SetAppInfo("test", "0.0.0")
-	if _, err := s.ListBuckets(); err != nil {
+	s = s.WithEnvironments(map[string]string{"dev": "dev.bucket"})
+	if bucket, err := s.Bucket("dev"); err != nil || bucket != "dev.bucket" {
+		t.Errorf("case 1 got %v, %v", bucket, err)
+	}
+}
+
+func TestNewFromBackend(t *testing.T) {
+	s := space.NewFromBackend(space.NewMemoryBackend("foo"))
+	buckets, err := s.ListBuckets(context.Background())
+	if err != nil {
 		t.Errorf("case 1 got %v", err)
 	}
+	if len(buckets) != 1 || buckets[0].Name != "foo" {
+		t.Errorf("case 1 got %v, want bucket foo", buckets)
+	}
 }
 
 func setupSpace(t *testing.T) (space.Space, string) {
@@ -42,7 +149,7 @@ func setupSpace(t *testing.T) (space.Space, string) {
 	if err != nil {
 		t.Errorf("setup space fail: %v", err)
 	}
-	bucket, err := service.GetBucket("dev")
+	bucket, err := s.Bucket("dev")
 	if err != nil {
 		t.Errorf("setup bucket fail: %v", err)
 	}
@@ -51,7 +158,7 @@ func setupSpace(t *testing.T) (space.Space, string) {
 
 func TestListBuckets(t *testing.T) {
 	s, _ := setupSpace(t)
-	_, err := s.ListBuckets()
+	_, err := s.ListBuckets(context.Background())
 	if err != nil {
 		t.Errorf("case 1 got %v", err)
 	}
@@ -67,7 +174,7 @@ func TestListObjects(t *testing.T) {
 		t.Errorf("setup put file fail: %v", err)
 	}
 
-	objectNames, err := s.ListObjects(bucket, "test", true)
+	objectNames, err := s.ListObjects(context.Background(), bucket, "test", true)
 	if err != nil {
 		t.Errorf("case 1 got %v", err)
 	}
@@ -81,12 +188,104 @@ func TestListObjects(t *testing.T) {
 		t.Errorf("case 1 object found is %v,, want true", found)
 	}
 
-	err = s.Remove(bucket, objectName)
+	err = s.Remove(context.Background(), bucket, objectName)
 	if err != nil {
 		t.Errorf("teardown fail: %v", err)
 	}
 }
 
+func TestWalkObjects(t *testing.T) {
+	s := space.NewFromBackend(space.NewMemoryBackend("dev.bucket")).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"})
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if err := setupPut(fmt.Sprintf("walk/%v.txt", i), "test", s, "dev.bucket"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := []string{}
+	err := s.Walk(ctx, "dev", space.ListOptions{Prefix: "walk/", PageSize: 2}, func(object space.ObjectInfo) error {
+		keys = append(keys, object.Key)
+		return nil
+	})
+	if err != nil || len(keys) != 5 || keys[4] != "walk/4.txt" {
+		t.Errorf("case 1 got %v, %v, want 5 objects", keys, err)
+	}
+
+	keys = keys[:0]
+	err = s.Walk(ctx, "dev", space.ListOptions{Prefix: "walk/", StartAfter: "walk/1.txt", PageSize: 2}, func(object space.ObjectInfo) error {
+		if len(keys) == 2 {
+			return space.ErrStopWalk
+		}
+		keys = append(keys, object.Key)
+		return nil
+	})
+	if err != nil || len(keys) != 2 || keys[0] != "walk/2.txt" {
+		t.Errorf("case 2 got %v, %v, want walk/2.txt and walk/3.txt", keys, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = s.Walk(ctx, "dev", space.ListOptions{Prefix: "walk/", PageSize: 2}, func(object space.ObjectInfo) error {
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("case 3 got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Now()
+	object := space.ObjectInfo{Key: "backup/db.tar.gz", Size: 200 << 20, LastModified: now.Add(-40 * 24 * time.Hour)}
+	tags := func() (map[string]string, error) {
+		return map[string]string{"type": "backup"}, nil
+	}
+
+	cases := []struct {
+		filter space.Filter
+		want   bool
+	}{
+		{space.Glob("*.tar.gz"), true},
+		{space.Glob("backup/*.zip"), false},
+		{space.LargerThan(100 << 20), true},
+		{space.SmallerThan(100 << 20), false},
+		{space.OlderThan(30 * 24 * time.Hour), true},
+		{space.NewerThan(30 * 24 * time.Hour), false},
+		{space.HasTag("type", "backup"), true},
+		{space.HasTag("type", "log"), false},
+		{space.All(space.Glob("*.gz"), space.LargerThan(1)), true},
+		{space.All(space.Glob("*.gz"), space.SmallerThan(1)), false},
+		{space.Any(space.Glob("*.zip"), space.HasTag("type", "backup")), true},
+		{space.Not(space.Glob("*.gz")), false},
+	}
+	for i, c := range cases {
+		if got, err := c.filter(object, tags); err != nil || got != c.want {
+			t.Errorf("case %v got %v, %v, want %v", i+1, got, err, c.want)
+		}
+	}
+
+	s := space.NewFromBackend(space.NewMemoryBackend("dev.bucket")).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"})
+	setupPut("filter/a.txt", "a", s, "dev.bucket")
+	setupPut("filter/b.log", "b", s, "dev.bucket")
+	s.PutTag(context.Background(), "dev.bucket", "filter/b.log", map[string]string{"keep": "no"})
+
+	keys := []string{}
+	s.WithFilter(space.HasTag("keep", "no")).Walk(context.Background(), "dev", space.ListOptions{Prefix: "filter/"}, func(object space.ObjectInfo) error {
+		keys = append(keys, object.Key)
+		return nil
+	})
+	if len(keys) != 1 || keys[0] != "filter/b.log" {
+		t.Errorf("case %v got %v, want filter/b.log", len(cases)+1, keys)
+	}
+
+	removed, err := s.WithFilter(space.Glob("*.log")).RemoveFiles(context.Background(), "dev", []string{"filter/a.txt", "filter/b.log"})
+	if err != nil || len(removed) != 1 || removed[0] != "filter/b.log" {
+		t.Errorf("case %v got %v, %v, want filter/b.log removed", len(cases)+2, removed, err)
+	}
+}
+
 func setupPut(objectName, objectContent string, s space.Space, bucket string) error {
 	content := strings.NewReader(objectContent)
 	length, err := s.Put(context.Background(), bucket, objectName, content, content.Size(), space.PutObjectOptions{})
@@ -100,7 +299,7 @@ func setupPut(objectName, objectContent string, s space.Space, bucket string) er
 }
 
 func teardownPut(objectName string, s space.Space, bucket string) error {
-	err := s.Remove(bucket, objectName)
+	err := s.Remove(context.Background(), bucket, objectName)
 	if err != nil {
 		return fmt.Errorf("put teardown got %v", err)
 	}
@@ -215,11 +414,17 @@ func TestGetFile(t *testing.T) {
 	if rstr != ostr {
 		t.Errorf("case 1 got %v, want %v", rstr, ostr)
 	}
+	originalFile.Close()
+	remoteFile.Close()
 
 	err = teardownPut(objectName, s, bucket)
 	if err != nil {
 		t.Error(err)
 	}
+	err = os.RemoveAll("./tmp")
+	if err != nil {
+		t.Error(err)
+	}
 }
 
 func TestStat(t *testing.T) {
@@ -231,7 +436,7 @@ func TestStat(t *testing.T) {
 		t.Error(err)
 	}
 
-	info, err := s.Stat(bucket, objectName, space.StatObjectOptions{})
+	info, err := s.Stat(context.Background(), bucket, objectName, space.StatObjectOptions{})
 	if err != nil {
 		t.Errorf("case 1 got error %v", err)
 	}
@@ -269,6 +474,171 @@ func TestRemoveObjects(t *testing.T) {
 	}
 }
 
+// denyingBackend fails to remove objects whose name contains "deny".
+type denyingBackend struct {
+	space.Backend
+}
+
+func (b denyingBackend) RemoveObjects(ctx context.Context, bucketName string, objectNames []string) (errs []space.RemoveObjectError) {
+	allowed := []string{}
+	for _, name := range objectNames {
+		if strings.Contains(name, "deny") {
+			errs = append(errs, space.RemoveObjectError{ObjectName: name, Err: minio.ErrorResponse{Code: "AccessDenied", Message: "Access Denied."}})
+			continue
+		}
+		allowed = append(allowed, name)
+	}
+	return append(errs, b.Backend.RemoveObjects(ctx, bucketName, allowed)...)
+}
+
+func TestRemoveObjectsBatchError(t *testing.T) {
+	s := space.NewFromBackend(denyingBackend{space.NewMemoryBackend("dev.bucket")}).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"})
+	objectNames := []string{"a.txt", "deny/b.txt", "c.txt"}
+	for _, objectName := range objectNames {
+		if err := setupPut(objectName, "test", s, "dev.bucket"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := s.RemoveFiles(context.Background(), "dev", objectNames)
+	var bErr *space.BatchError
+	if !errors.As(err, &bErr) {
+		t.Fatalf("case 1 got %v, want *BatchError", err)
+	}
+	if bErr.Total != 3 || len(bErr.Failed) != 1 || bErr.Failed[0].ObjectName != "deny/b.txt" || bErr.Failed[0].Code != "AccessDenied" {
+		t.Errorf("case 1 got %+v, want deny/b.txt failed with AccessDenied", bErr)
+	}
+	if len(removed) != 2 || removed[0] != "a.txt" || removed[1] != "c.txt" {
+		t.Errorf("case 1 got removed %v, want a.txt and c.txt", removed)
+	}
+	if strings.Contains(err.Error(), "<nil>") || !strings.Contains(err.Error(), "deny/b.txt") {
+		t.Errorf("case 1 got message %q", err)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	s := space.NewFromBackend(denyingBackend{space.NewMemoryBackend("dev.bucket")}).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"})
+
+	_, err := s.Stat(context.Background(), "dev.bucket", "missing.txt", space.StatObjectOptions{})
+	var resp minio.ErrorResponse
+	if !errors.Is(err, space.ErrNotFound) || !errors.As(err, &resp) || resp.Code != "NoSuchKey" {
+		t.Errorf("case 1 got %v, want ErrNotFound keeping NoSuchKey", err)
+	}
+	if _, err = s.Stat(context.Background(), "missing.bucket", "a.txt", space.StatObjectOptions{}); !errors.Is(err, space.ErrBucketNotFound) || errors.Is(err, space.ErrNotFound) {
+		t.Errorf("case 2 got %v, want ErrBucketNotFound", err)
+	}
+	if _, err = s.Bucket("staging"); !errors.Is(err, space.ErrInvalidEnv) {
+		t.Errorf("case 3 got %v, want ErrInvalidEnv", err)
+	}
+
+	if err = setupPut("deny/a.txt", "test", s, "dev.bucket"); err != nil {
+		t.Fatal(err)
+	}
+	err = s.RemoveObjects(context.Background(), "dev.bucket", []string{"deny/a.txt"})
+	var bErr *space.BatchError
+	if !errors.As(err, &bErr) || !errors.Is(bErr.Failed[0], space.ErrAccessDenied) {
+		t.Errorf("case 4 got %v, want ErrAccessDenied", err)
+	}
+}
+
+// unavailableBackend fails to stat objects until `failures` run out.
+type unavailableBackend struct {
+	space.Backend
+	failures *int
+	attempts *int
+}
+
+func (b unavailableBackend) StatObject(ctx context.Context, bucketName, objectName string, options space.StatObjectOptions) (space.ObjectInfo, error) {
+	*b.attempts++
+	if *b.failures > 0 {
+		*b.failures--
+		return space.ObjectInfo{}, minio.ErrorResponse{StatusCode: 503, Code: "SlowDown", Message: "Please reduce your request rate."}
+	}
+	return b.Backend.StatObject(ctx, bucketName, objectName, options)
+}
+
+func TestRetry(t *testing.T) {
+	failures, attempts := 2, 0
+	policy := space.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Jitter: 0.5}
+	s := space.NewFromBackend(unavailableBackend{space.NewMemoryBackend("dev.bucket"), &failures, &attempts}).WithRetry(policy)
+	ctx := context.Background()
+	if err := setupPut("retry.txt", "test", s, "dev.bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Stat(ctx, "dev.bucket", "retry.txt", space.StatObjectOptions{}); err != nil || attempts != 3 {
+		t.Errorf("case 1 got %v after %v attempts, want success after 3", err, attempts)
+	}
+
+	failures, attempts = 3, 0
+	if _, err := s.Stat(ctx, "dev.bucket", "retry.txt", space.StatObjectOptions{}); err == nil || attempts != 3 {
+		t.Errorf("case 2 got %v after %v attempts, want failure after 3", err, attempts)
+	}
+
+	failures, attempts = 0, 0
+	if _, err := s.Stat(ctx, "dev.bucket", "missing.txt", space.StatObjectOptions{}); !errors.Is(err, space.ErrNotFound) || attempts != 1 {
+		t.Errorf("case 3 got %v after %v attempts, want not found without retry", err, attempts)
+	}
+
+	failures, attempts = 1, 0
+	if _, err := s.WithRetry(space.NoRetry).Stat(ctx, "dev.bucket", "retry.txt", space.StatObjectOptions{}); err == nil || attempts != 1 {
+		t.Errorf("case 4 got %v after %v attempts, want failure without retry", err, attempts)
+	}
+
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("connection reset"), true},
+		{minio.ErrorResponse{StatusCode: 500, Code: "InternalError"}, true},
+		{minio.ErrorResponse{StatusCode: 400, Code: "InvalidArgument"}, false},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), false},
+		{&os.PathError{Op: "open", Path: "a.txt", Err: os.ErrNotExist}, false},
+	}
+	for i, c := range cases {
+		if got := space.IsRetryable(c.err); got != c.want {
+			t.Errorf("case %v got %v, want %v", i+5, got, c.want)
+		}
+	}
+}
+
+func TestShare(t *testing.T) {
+	profile := config.Default().Profile
+	profile.Endpoint, profile.Region, profile.Insecure = "localhost:9000", "us-east-1", true
+	profile.Key, profile.Secret, profile.Credentials = "key", "secret", []string{config.CredentialsStatic}
+	profile.Environments = map[string]string{"dev": "dev.bucket", "live": "live.bucket"}
+	s, err := space.NewFromProfile(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	u, err := s.PresignGet(ctx, "dev.bucket", "build/app.tar.gz", time.Hour)
+	if err != nil || u.Host != "localhost:9000" || u.Path != "/dev.bucket/build/app.tar.gz" || u.Query().Get("X-Amz-Expires") != "3600" {
+		t.Errorf("case 1 got %v, %v, want presigned URL expiring in an hour", u, err)
+	}
+	if _, err = s.PresignPut(ctx, "dev.bucket", "upload.txt", 8*24*time.Hour); err == nil {
+		t.Error("case 2 got no error, want expiry over a week to be invalid")
+	}
+
+	shared, err := s.Share(ctx, "dev", "build/app.tar.gz", 72*time.Hour, true)
+	if err != nil || shared.Method != http.MethodPut || !strings.Contains(shared.URL, "X-Amz-Expires=259200") || time.Until(shared.Expires) < 71*time.Hour {
+		t.Errorf("case 3 got %+v, %v, want upload URL expiring in 72h", shared, err)
+	}
+	if _, err = s.Share(ctx, "live", "build/app.tar.gz", 72*time.Hour, false); err == nil {
+		t.Error("case 4 got no error, want live limited to 24h")
+	}
+	if _, err = s.Share(ctx, "prod", "build/app.tar.gz", time.Hour, false); !errors.Is(err, space.ErrInvalidEnv) {
+		t.Errorf("case 5 got %v, want invalid environment", err)
+	}
+
+	if _, err = space.NewFromBackend(space.NewMemoryBackend()).PresignGet(ctx, "dev.bucket", "a.txt", time.Hour); err == nil {
+		t.Error("case 6 got no error, want presigned URL to be unsupported by memory backend")
+	}
+}
+
 func TestTag(t *testing.T) {
 	s, bucket := setupSpace(t)
 	objectName := "test/tag.txt"
@@ -0,0 +1,264 @@
+package space
+
+// Two-way sync between a local folder and a prefix, transferring only changed files.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SyncOp is what `Sync` does with a single file.
+type SyncOp string
+
+// Operations planned by `PlanPush` and `PlanPull`.
+const (
+	SyncUpload       SyncOp = "upload"
+	SyncDownload     SyncOp = "download"
+	SyncRemoveObject SyncOp = "remove-object"
+	SyncRemoveFile   SyncOp = "remove-file"
+)
+
+// SyncAction planned for a single file, `Reason` is one of "new", "size", "checksum" or "extraneous".
+type SyncAction struct {
+	Op         SyncOp
+	Path       string
+	ObjectName string
+	Reason     string
+}
+
+// WithDelete plans removal of files missing from the sync source.
+func (s Space) WithDelete(deleteExtra bool) Space {
+	s.deleteExtra = deleteExtra
+	return s
+}
+
+// PlanPush compares files in `folder` with objects under `prefix`, planning uploads of new or changed files.
+// Files ignored by `.spaceignore` files or `WithIgnore` are skipped, objects matched by `WithIgnore` are kept.
+// `WithFilter` selects both local files, as if already uploaded, and objects.
+// With `WithDelete`, objects without local file are removed, so `prefix` is required rather than the whole bucket.
+func (s Space) PlanPush(ctx context.Context, folder, env, prefix string) (actions []SyncAction, err error) {
+	bucket, err := s.Bucket(env)
+	if err != nil {
+		return
+	}
+	if s.deleteExtra && prefix == "" {
+		return nil, fmt.Errorf("Invalid prefix, sync with delete needs a prefix")
+	}
+	prefix = folderPrefix(prefix)
+
+	objects, err := s.syncObjects(ctx, bucket, prefix)
+	if err != nil {
+		return
+	}
+	remote := map[string]ObjectInfo{}
+	for _, object := range objects {
+		remote[object.Key] = object
+	}
+
+	filePaths, err := s.walkFolder(folder)
+	if err != nil {
+		return
+	}
+	local := map[string]bool{}
+	for _, filePath := range filePaths {
+		relativePath, errr := filepath.Rel(folder, filePath)
+		if errr != nil {
+			return nil, errr
+		}
+		objectName := prefix + filepath.ToSlash(relativePath)
+		fi, errr := os.Stat(filePath)
+		if errr != nil {
+			return nil, errr
+		}
+		ok, errr := s.selectedFile(objectName, fi)
+		if errr != nil {
+			return nil, errr
+		}
+		if !ok {
+			continue
+		}
+		local[objectName] = true
+		reason := "new"
+		if object, ok := remote[objectName]; ok {
+			reason, err = s.compare(ctx, bucket, filePath, fi, object, fi.ModTime().After(object.LastModified))
+			if err != nil {
+				return nil, err
+			}
+		}
+		if reason != "" {
+			actions = append(actions, SyncAction{Op: SyncUpload, Path: filePath, ObjectName: objectName, Reason: reason})
+		}
+	}
+
+	if !s.deleteExtra {
+		return
+	}
+	for _, object := range objects {
+		if !local[object.Key] {
+			actions = append(actions, SyncAction{Op: SyncRemoveObject, ObjectName: object.Key, Reason: "extraneous"})
+		}
+	}
+	return
+}
+
+// PlanPull compares objects under `prefix` with files in `folder`, planning downloads of new or changed objects.
+// Objects matched by `WithIgnore` are skipped, so are local files ignored by `.spaceignore` files or `WithIgnore`.
+// With `WithDelete`, local files without object are removed.
+func (s Space) PlanPull(ctx context.Context, prefix, folder, env string) (actions []SyncAction, err error) {
+	bucket, err := s.Bucket(env)
+	if err != nil {
+		return
+	}
+	prefix = folderPrefix(prefix)
+
+	objects, err := s.syncObjects(ctx, bucket, prefix)
+	if err != nil {
+		return
+	}
+
+	remote := map[string]bool{}
+	for _, object := range objects {
+		filePath, errr := localPath(folder, prefix, object.Key)
+		if errr != nil {
+			return nil, errr
+		}
+		remote[filePath] = true
+
+		reason := "new"
+		fi, errr := os.Stat(filePath)
+		if errr == nil {
+			reason, err = s.compare(ctx, bucket, filePath, fi, object, object.LastModified.After(fi.ModTime()))
+			if err != nil {
+				return nil, err
+			}
+		} else if !os.IsNotExist(errr) {
+			return nil, errr
+		}
+		if reason != "" {
+			actions = append(actions, SyncAction{Op: SyncDownload, Path: filePath, ObjectName: object.Key, Reason: reason})
+		}
+	}
+
+	if !s.deleteExtra {
+		return
+	}
+	if _, err = os.Stat(folder); os.IsNotExist(err) {
+		return actions, nil
+	}
+	filePaths, err := s.walkFolder(folder)
+	if err != nil {
+		return
+	}
+	for _, filePath := range filePaths {
+		if remote[filePath] {
+			continue
+		}
+		relativePath, errr := filepath.Rel(folder, filePath)
+		if errr != nil {
+			return nil, errr
+		}
+		fi, errr := os.Stat(filePath)
+		if errr != nil {
+			return nil, errr
+		}
+		ok, errr := s.selectedFile(prefix+filepath.ToSlash(relativePath), fi)
+		if errr != nil {
+			return nil, errr
+		}
+		if ok {
+			actions = append(actions, SyncAction{Op: SyncRemoveFile, Path: filePath, Reason: "extraneous"})
+		}
+	}
+	return
+}
+
+// Sync runs planned actions concurrently, see `WithJobs`. Actions are reported with `WithReport`,
+// failed ones don't stop the sync and are returned as `*TransferError`.
+func (s Space) Sync(ctx context.Context, env string, actions []SyncAction) (done []SyncAction, err error) {
+	bucket, err := s.Bucket(env)
+	if err != nil {
+		return
+	}
+
+	planned := make([]TransferResult, len(actions))
+	for i, action := range actions {
+		planned[i] = TransferResult{Path: action.Path, ObjectName: action.ObjectName, Op: action.Op}
+	}
+
+	results, err := s.transfer(ctx, planned, func(result TransferResult) TransferResult {
+		switch result.Op {
+		case SyncUpload:
+			_, result.Err = s.Upload(ctx, result.Path, env, path.Dir(result.ObjectName))
+		case SyncDownload:
+			result.Err = s.GetFile(ctx, bucket, result.ObjectName, result.Path, GetObjectOptions{})
+		case SyncRemoveObject:
+			result.Err = s.backend.RemoveObject(ctx, bucket, result.ObjectName)
+		case SyncRemoveFile:
+			result.Err = os.Remove(result.Path)
+		}
+		return result
+	})
+	for i, result := range results {
+		if result.Err == nil {
+			done = append(done, actions[i])
+		}
+	}
+	return
+}
+
+// syncObjects under `prefix` selected by `WithFilter`, without folder markers and objects matched by `WithIgnore`.
+func (s Space) syncObjects(ctx context.Context, bucket, prefix string) (objects []ObjectInfo, err error) {
+	listed, err := s.backend.ListObjects(ctx, bucket, prefix, true)
+	if err != nil {
+		return
+	}
+	for _, object := range listed {
+		if strings.HasSuffix(object.Key, "/") || s.matcher.Match(strings.TrimPrefix(object.Key, prefix), false) {
+			continue
+		}
+		ok, errr := s.selected(ctx, bucket, object)
+		if errr != nil {
+			return nil, errr
+		}
+		if ok {
+			objects = append(objects, object)
+		}
+	}
+	return
+}
+
+// compare a local file with an object, returning why it has changed or empty if it hasn't.
+// Files of the same size are only checksummed when the source is newer.
+func (s Space) compare(ctx context.Context, bucket, filePath string, fi os.FileInfo, object ObjectInfo, sourceNewer bool) (reason string, err error) {
+	if fi.Size() != object.Size {
+		return "size", nil
+	}
+	if !sourceNewer {
+		return "", nil
+	}
+
+	sum, err := FileChecksum(filePath)
+	if err != nil {
+		return
+	}
+	etag := strings.Trim(object.ETag, `"`)
+	if !strings.Contains(etag, "-") {
+		if etag != sum.MD5 {
+			return "checksum", nil
+		}
+		return "", nil
+	}
+
+	info, err := s.backend.StatObject(ctx, bucket, object.Key, StatObjectOptions{})
+	if err != nil {
+		return
+	}
+	if stored := info.UserMetadata[ChecksumMetadata]; stored == "" || stored != sum.SHA256 {
+		return "checksum", nil
+	}
+	return "", nil
+}
@@ -5,45 +5,61 @@ package space
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 
-	"github.com/lebenasa/space/service"
+	"github.com/lebenasa/space/ignore"
 )
 
+// WithIgnore skips files matched by `matcher` in `UploadFolder`.
+// Its patterns take precedence over `.spaceignore` files found in the folder and `ignore.Defaults`.
+func (s Space) WithIgnore(matcher *ignore.Matcher) Space {
+	s.matcher = matcher
+	return s
+}
+
 // WithTags that will be set to all files uploaded with `Upload*` functions.
 func (s Space) WithTags(tags map[string]string) Space {
 	s.tags = tags
 	return s
 }
 
-func (s Space) List(env, prefix string) (objects []ObjectInfo, err error) {
-	bucket, err := service.GetBucket(env)
+// List every object under `prefix` in an environment.
+func (s Space) List(ctx context.Context, env, prefix string) (objects []ObjectInfo, err error) {
+	bucket, err := s.Bucket(env)
 	if err != nil {
 		return
 	}
-	objects, err = s.ListObjects(bucket, prefix, true)
+	objects, err = s.ListObjects(ctx, bucket, prefix, true)
 	return
 }
 
 // UploadFile into Space. For large file (>100 MB) please use `UploadBigFile`.
 // If Space is created using `WithTags`, apply those tags into uploaded file.
-// Requires generated `service` module that's not tracked by git.
+// With `WithVerify`, uploaded file is checked against its local checksum.
 func (s Space) UploadFile(ctx context.Context, fp, env, prefix string) (objectName string, err error) {
-	bucket, err := service.GetBucket(env)
+	bucket, err := s.Bucket(env)
 	if err != nil {
 		return
 	}
 	filename := filepath.Base(fp)
 	objectName = path.Join(prefix, filename)
 
-	_, err = s.PutFile(ctx, bucket, objectName, fp, PutObjectOptions{
-		ContentType: "application/octet-stream",
-	})
+	options, sum, err := s.uploadOptions(fp)
 	if err != nil {
 		return
 	}
+	if _, err = s.PutFile(ctx, bucket, objectName, fp, options); err != nil {
+		return
+	}
+	if s.verify {
+		if err = s.verifyUpload(ctx, bucket, objectName, sum); err != nil {
+			return
+		}
+	}
 
 	if len(s.tags) == 0 {
 		return
@@ -52,49 +68,87 @@ func (s Space) UploadFile(ctx context.Context, fp, env, prefix string) (objectNa
 	return
 }
 
-// UploadFolder into Space. Do not use if there's a large file (>100 MB) inside the folder.
-// Requires generated `service` module that's not tracked by git.
+// UploadFolder into Space concurrently, see `WithJobs`. Large files are uploaded with `UploadBigFile`.
+// Files matched by `.spaceignore` files (nested, like `.gitignore`) or `WithIgnore` are skipped.
+// Failed files don't stop the upload, they're reported with `WithReport` and returned as `*TransferError`.
 func (s Space) UploadFolder(ctx context.Context, folder, env, prefix string) (objectNames []string, err error) {
-	filePaths := []string{}
-	filepath.Walk(folder, func(fpath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			return nil
-		}
-
-		// TODO: skips ignored files
-		filePaths = append(filePaths, fpath)
-
-		return nil
-	})
+	filePaths, err := s.walkFolder(folder)
+	if err != nil {
+		return
+	}
 
 	objectPrefix := prefix
 	if objectPrefix == "" {
 		objectPrefix = filepath.Dir(folder)
 	}
 
-	// TODO: do this concurrently
-	for _, filePath := range filePaths {
+	planned := make([]TransferResult, len(filePaths))
+	for i, filePath := range filePaths {
 		relativePath, errr := filepath.Rel(folder, filePath)
 		if errr != nil {
 			return objectNames, errr
 		}
-		relativePrefix := path.Join(objectPrefix, filepath.ToSlash(filepath.Dir(relativePath)))
-		objectName, errr := s.UploadFile(ctx, filePath, env, relativePrefix)
-		if errr != nil {
-			return objectNames, errr
+		planned[i] = TransferResult{
+			Path:       filePath,
+			ObjectName: path.Join(objectPrefix, filepath.ToSlash(relativePath)),
+		}
+	}
+
+	results, err := s.transfer(ctx, planned, func(result TransferResult) TransferResult {
+		_, result.Err = s.Upload(ctx, result.Path, env, path.Dir(result.ObjectName))
+		return result
+	})
+	for _, result := range results {
+		if result.Err == nil {
+			objectNames = append(objectNames, result.ObjectName)
 		}
-		objectNames = append(objectNames, objectName)
 	}
 
 	return
 }
 
+// walkFolder lists files to upload, skipping ignored files and folders.
+func (s Space) walkFolder(folder string) (filePaths []string, err error) {
+	fileMatcher := ignore.New(ignore.Defaults...)
+	err = filepath.Walk(folder, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(folder, fpath)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(relativePath)
+
+		if name != "." {
+			ignored, matched := s.matcher.Check(name, info.IsDir())
+			if !matched {
+				ignored = fileMatcher.Match(name, info.IsDir())
+			}
+			if ignored && info.IsDir() {
+				return filepath.SkipDir
+			}
+			if ignored {
+				return nil
+			}
+		}
+
+		if info.IsDir() {
+			return fileMatcher.AddFile(name, filepath.Join(fpath, ignore.FileName))
+		}
+		if info.Name() == ignore.FileName {
+			return nil
+		}
+
+		filePaths = append(filePaths, fpath)
+		return nil
+	})
+	return filePaths, err
+}
+
 // DownloadFile from Space.
 func (s Space) DownloadFile(ctx context.Context, objectName, filePath, env string) error {
-	bucket, err := service.GetBucket(env)
+	bucket, err := s.Bucket(env)
 	if err != nil {
 		return err
 	}
@@ -102,13 +156,107 @@ func (s Space) DownloadFile(ctx context.Context, objectName, filePath, env strin
 	return s.GetFile(ctx, bucket, objectName, filePath, GetObjectOptions{})
 }
 
-// RemoveFiles from Space.
-func (s Space) RemoveFiles(ctx context.Context, env string, objectNames []string) (err error) {
-	bucket, err := service.GetBucket(env)
+// DownloadFolder downloads every object under `prefix` into `folder` concurrently, see `WithJobs`.
+// Object names relative to `prefix` are recreated as sub-folders of `folder`, see `WithFilter` to select objects.
+// Failed files don't stop the download, they're reported with `WithReport` and returned as `*TransferError`.
+func (s Space) DownloadFolder(ctx context.Context, prefix, folder, env string) (filePaths []string, err error) {
+	bucket, err := s.Bucket(env)
+	if err != nil {
+		return
+	}
+	prefix = folderPrefix(prefix)
+
+	objects, err := s.backend.ListObjects(ctx, bucket, prefix, true)
 	if err != nil {
 		return
 	}
 
-	err = s.RemoveObjects(ctx, bucket, objectNames)
-	return err
+	planned := []TransferResult{}
+	for _, object := range objects {
+		if strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+		if ok, errr := s.selected(ctx, bucket, object); errr != nil || !ok {
+			if errr != nil {
+				return filePaths, errr
+			}
+			continue
+		}
+		filePath, errr := localPath(folder, prefix, object.Key)
+		if errr != nil {
+			return filePaths, errr
+		}
+		planned = append(planned, TransferResult{Path: filePath, ObjectName: object.Key})
+	}
+
+	results, err := s.transfer(ctx, planned, func(result TransferResult) TransferResult {
+		result.Err = s.GetFile(ctx, bucket, result.ObjectName, result.Path, GetObjectOptions{})
+		return result
+	})
+	for _, result := range results {
+		if result.Err == nil {
+			filePaths = append(filePaths, result.Path)
+		}
+	}
+
+	return
+}
+
+// folderPrefix treats `prefix` as a folder, so "foo" doesn't match "foobar.txt".
+func folderPrefix(prefix string) string {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
+
+// localPath of an object under `prefix` once downloaded into `folder`.
+func localPath(folder, prefix, objectName string) (string, error) {
+	filePath := filepath.Join(folder, filepath.FromSlash(strings.TrimPrefix(objectName, prefix)))
+	if relativePath, err := filepath.Rel(folder, filePath); err != nil || strings.HasPrefix(relativePath, "..") {
+		return "", fmt.Errorf("Invalid object name %v, it's outside of %v", objectName, folder)
+	}
+	return filePath, nil
+}
+
+// RemoveFiles from Space. With `WithFilter`, only selected objects are removed.
+// Returns removed objects, the others are listed in `*BatchError`.
+func (s Space) RemoveFiles(ctx context.Context, env string, objectNames []string) (removed []string, err error) {
+	bucket, err := s.Bucket(env)
+	if err != nil {
+		return
+	}
+
+	removed = objectNames
+	if s.filter != nil {
+		removed = nil
+		for _, objectName := range objectNames {
+			info, err := s.backend.StatObject(ctx, bucket, objectName, StatObjectOptions{})
+			if err != nil {
+				return nil, err
+			}
+			ok, err := s.selected(ctx, bucket, info)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				removed = append(removed, objectName)
+			}
+		}
+	}
+
+	err = s.RemoveObjects(ctx, bucket, removed)
+	if bErr, ok := err.(*BatchError); ok {
+		failed := bErr.FailedNames()
+		succeeded := []string{}
+		for _, objectName := range removed {
+			if !failed[objectName] {
+				succeeded = append(succeeded, objectName)
+			}
+		}
+		removed = succeeded
+	} else if err != nil {
+		removed = nil
+	}
+	return removed, err
 }
@@ -0,0 +1,630 @@
+package space_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lebenasa/space"
+	"github.com/lebenasa/space/ignore"
+)
+
+// failingBackend fails to put objects whose name contains "fail".
+type failingBackend struct {
+	space.Backend
+}
+
+func (b failingBackend) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, options space.PutObjectOptions) (int64, error) {
+	if strings.Contains(objectName, "fail") {
+		return 0, errors.New("injected failure")
+	}
+	return b.Backend.PutObject(ctx, bucketName, objectName, reader, objectSize, options)
+}
+
+func setupFolder(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "space-folder")
+	if err != nil {
+		t.Fatalf("setup folder fail: %v", err)
+	}
+	for name, content := range files {
+		fp := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatalf("setup folder fail: %v", err)
+		}
+		if err = ioutil.WriteFile(fp, []byte(content), 0644); err != nil {
+			t.Fatalf("setup folder fail: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestUploadFolder(t *testing.T) {
+	folder := setupFolder(t, map[string]string{
+		"a.txt":        "a",
+		"foo/b.txt":    "b",
+		"foo/fail.txt": "c",
+		"bar/fail.txt": "d",
+	})
+	defer os.RemoveAll(folder)
+
+	mu := sync.Mutex{}
+	reported := []string{}
+	s := space.NewFromBackend(failingBackend{space.NewMemoryBackend("dev.bucket")}).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"}).
+		WithJobs(2).
+		WithReport(func(result space.TransferResult) {
+			mu.Lock()
+			reported = append(reported, result.ObjectName)
+			mu.Unlock()
+		})
+
+	objectNames, err := s.UploadFolder(context.Background(), folder, "dev", "test")
+	sort.Strings(objectNames)
+	if len(objectNames) != 2 || objectNames[0] != "test/a.txt" || objectNames[1] != "test/foo/b.txt" {
+		t.Errorf("case 1 got %v, want test/a.txt and test/foo/b.txt", objectNames)
+	}
+	if len(reported) != 4 {
+		t.Errorf("case 1 reported %v, want 4 files", reported)
+	}
+
+	var tErr *space.TransferError
+	if !errors.As(err, &tErr) {
+		t.Fatalf("case 1 got error %v, want *TransferError", err)
+	}
+	if len(tErr.Failed) != 2 {
+		t.Errorf("case 1 got %v failures, want 2", len(tErr.Failed))
+	}
+	for _, result := range tErr.Failed {
+		if !strings.Contains(err.Error(), result.Path) {
+			t.Errorf("case 1 error %q doesn't list %v", err, result.Path)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	objectNames, err = s.UploadFolder(ctx, folder, "dev", "test")
+	if len(objectNames) != 0 || err == nil {
+		t.Errorf("case 2 got %v, %v, want cancelled upload", objectNames, err)
+	}
+}
+
+func TestUploadFolderIgnore(t *testing.T) {
+	folder := setupFolder(t, map[string]string{
+		".spaceignore":          "*.log\n.git/\n",
+		".git/HEAD":             "ref",
+		"app.log":               "log",
+		"main.go":               "package main",
+		"keep/important.log":    "log",
+		"sub/.spaceignore":      "*.tmp\n",
+		"sub/a.tmp":             "tmp",
+		"sub/a.go":              "package sub",
+		"excluded/whatever.txt": "x",
+	})
+	defer os.RemoveAll(folder)
+
+	s := space.NewFromBackend(space.NewMemoryBackend("dev.bucket")).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"}).
+		WithIgnore(ignore.New("excluded/", "!keep/*.log"))
+
+	objectNames, err := s.UploadFolder(context.Background(), folder, "dev", "test")
+	if err != nil {
+		t.Fatalf("case 1 got error %v", err)
+	}
+	sort.Strings(objectNames)
+	want := []string{"test/keep/important.log", "test/main.go", "test/sub/a.go"}
+	if strings.Join(objectNames, ",") != strings.Join(want, ",") {
+		t.Errorf("case 1 got %v, want %v", objectNames, want)
+	}
+
+	repo := setupFolder(t, map[string]string{".git/HEAD": "ref", "main.go": "package main"})
+	defer os.RemoveAll(repo)
+	cases := []struct {
+		matcher *ignore.Matcher
+		want    string
+	}{
+		{nil, "repo/main.go"},
+		{ignore.New("!.git"), "repo/.git/HEAD,repo/main.go"},
+	}
+	for i, c := range cases {
+		objectNames, err = s.WithIgnore(c.matcher).UploadFolder(context.Background(), repo, "dev", "repo")
+		if sort.Strings(objectNames); err != nil || strings.Join(objectNames, ",") != c.want {
+			t.Errorf("case %v got %v, %v, want %v", i+2, objectNames, err, c.want)
+		}
+	}
+}
+
+// flakyBackend fails the first attempt of every part upload.
+type flakyBackend struct {
+	space.Backend
+	mu       *sync.Mutex
+	attempts map[int]int
+}
+
+func (b flakyBackend) PutObjectPart(ctx context.Context, bucketName, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (space.ObjectPart, error) {
+	b.mu.Lock()
+	b.attempts[partNumber]++
+	attempt := b.attempts[partNumber]
+	b.mu.Unlock()
+
+	if attempt == 1 {
+		ioutil.ReadAll(io.LimitReader(reader, partSize/2))
+		return space.ObjectPart{}, errors.New("injected failure")
+	}
+	return b.Backend.PutObjectPart(ctx, bucketName, objectName, uploadID, partNumber, reader, partSize)
+}
+
+func TestUploadBigFile(t *testing.T) {
+	content := strings.Repeat("0123456789abcdef", (space.MinPartSize*2+1024)/16)
+	folder := setupFolder(t, map[string]string{"big.bin": content})
+	defer os.RemoveAll(folder)
+
+	backend := flakyBackend{space.NewMemoryBackend("dev.bucket"), &sync.Mutex{}, map[int]int{}}
+	s := space.NewFromBackend(backend).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"}).
+		WithTags(map[string]string{"type": "archive"}).
+		WithPartSize(space.MinPartSize)
+
+	objectName, err := s.UploadBigFile(context.Background(), filepath.Join(folder, "big.bin"), "dev", "test")
+	if err != nil {
+		t.Fatalf("case 1 got error %v", err)
+	}
+	if len(backend.attempts) != 3 {
+		t.Errorf("case 1 uploaded %v parts, want 3", len(backend.attempts))
+	}
+
+	info, err := s.Stat(context.Background(), "dev.bucket", objectName, space.StatObjectOptions{})
+	if err != nil || info.Size != int64(len(content)) || !strings.HasSuffix(info.ETag, "-3") {
+		t.Errorf("case 1 got %v size %v etag %v, want multipart object", err, info.Size, info.ETag)
+	}
+	object, err := s.Get(context.Background(), "dev.bucket", objectName, space.GetObjectOptions{})
+	if err != nil {
+		t.Fatalf("case 1 got error %v", err)
+	}
+	data, _ := ioutil.ReadAll(object)
+	if string(data) != content {
+		t.Error("case 1 got different content")
+	}
+	if tags, _ := s.GetTags(context.Background(), "dev.bucket", objectName); tags["type"] != "archive" {
+		t.Errorf("case 1 got tags %v, want type: archive", tags)
+	}
+}
+
+// brokenBackend fails every upload of part `broken`.
+type brokenBackend struct {
+	space.Backend
+	broken   *int
+	uploaded *[]int
+}
+
+func (b brokenBackend) PutObjectPart(ctx context.Context, bucketName, objectName, uploadID string, partNumber int, reader io.Reader, partSize int64) (space.ObjectPart, error) {
+	if partNumber == *b.broken {
+		return space.ObjectPart{}, errors.New("injected failure")
+	}
+	*b.uploaded = append(*b.uploaded, partNumber)
+	return b.Backend.PutObjectPart(ctx, bucketName, objectName, uploadID, partNumber, reader, partSize)
+}
+
+func TestResumeUpload(t *testing.T) {
+	content := strings.Repeat("0123456789abcdef", (space.MinPartSize*2+1024)/16)
+	folder := setupFolder(t, map[string]string{"big.bin": content})
+	defer os.RemoveAll(folder)
+	stateDir := filepath.Join(folder, "state")
+
+	broken, uploaded := 2, []int{}
+	backend := brokenBackend{space.NewMemoryBackend("dev.bucket"), &broken, &uploaded}
+	s := space.NewFromBackend(backend).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"}).
+		WithPartSize(space.MinPartSize).
+		WithJobs(1).
+		WithStateDir(stateDir)
+	ctx := context.Background()
+	fp := filepath.Join(folder, "big.bin")
+
+	if _, err := s.UploadBigFile(ctx, fp, "dev", "test"); err == nil {
+		t.Fatal("case 1 got no error, want error")
+	}
+	uploads, err := s.IncompleteUploads(ctx, "dev", "test/")
+	if err != nil || len(uploads) != 1 {
+		t.Fatalf("case 1 got %v, %v, want 1 incomplete upload", uploads, err)
+	}
+	states, err := space.LoadUploadStates(stateDir)
+	if err != nil || len(states) != 1 || states[0].UploadID != uploads[0].UploadID {
+		t.Errorf("case 1 got states %v, %v, want upload %v", states, err, uploads[0].UploadID)
+	}
+
+	broken, uploaded = 0, []int{}
+	objectName, err := s.WithResume(true).UploadBigFile(ctx, fp, "dev", "test")
+	if err != nil {
+		t.Fatalf("case 2 got error %v", err)
+	}
+	for _, partNumber := range uploaded {
+		if partNumber == 1 {
+			t.Errorf("case 2 uploaded parts %v, want part 1 skipped", uploaded)
+		}
+	}
+	if info, err := s.Stat(context.Background(), "dev.bucket", objectName, space.StatObjectOptions{}); err != nil || info.Size != int64(len(content)) {
+		t.Errorf("case 2 got %v size %v, want %v", err, info.Size, len(content))
+	}
+	if states, _ = space.LoadUploadStates(stateDir); len(states) != 0 {
+		t.Errorf("case 2 got states %v, want none", states)
+	}
+
+	broken = 3
+	if _, err = s.UploadBigFile(ctx, fp, "dev", "test"); err == nil {
+		t.Fatal("case 3 got no error, want error")
+	}
+	uploads, _ = s.IncompleteUploads(ctx, "dev", "test/")
+	if len(uploads) != 1 {
+		t.Fatalf("case 3 got %v, want 1 incomplete upload", uploads)
+	}
+	if err = s.AbortUpload(ctx, "dev", uploads[0].Key, uploads[0].UploadID); err != nil {
+		t.Errorf("case 3 abort got %v", err)
+	}
+	if uploads, _ = s.IncompleteUploads(ctx, "dev", "test/"); len(uploads) != 0 {
+		t.Errorf("case 3 got %v after abort, want none", uploads)
+	}
+	if states, _ = space.LoadUploadStates(stateDir); len(states) != 0 {
+		t.Errorf("case 3 got states %v after abort, want none", states)
+	}
+}
+
+// corruptBackend stores every object with its last byte dropped.
+type corruptBackend struct {
+	space.Backend
+}
+
+func (b corruptBackend) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, options space.PutObjectOptions) (int64, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return 0, err
+	}
+	data = data[:len(data)-1]
+	return b.Backend.PutObject(ctx, bucketName, objectName, bytes.NewReader(data), int64(len(data)), options)
+}
+
+func TestUploadVerify(t *testing.T) {
+	content := strings.Repeat("0123456789abcdef", (space.MinPartSize*2+1024)/16)
+	folder := setupFolder(t, map[string]string{"a.txt": "test content", "big.bin": content})
+	defer os.RemoveAll(folder)
+	ctx := context.Background()
+	envs := map[string]string{"dev": "dev.bucket"}
+
+	s := space.NewFromBackend(space.NewMemoryBackend("dev.bucket")).
+		WithEnvironments(envs).
+		WithPartSize(space.MinPartSize).
+		WithVerify(true)
+	for i, name := range []string{"a.txt", "big.bin"} {
+		fp := filepath.Join(folder, name)
+		sum, err := space.FileChecksum(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objectName, err := s.Upload(ctx, fp, "dev", "test")
+		if err != nil {
+			t.Errorf("case %v got error %v", i+1, err)
+			continue
+		}
+		if info, _ := s.Stat(context.Background(), "dev.bucket", objectName, space.StatObjectOptions{}); info.UserMetadata[space.ChecksumMetadata] != sum.SHA256 {
+			t.Errorf("case %v got metadata %v, want %v: %v", i+1, info.UserMetadata, space.ChecksumMetadata, sum.SHA256)
+		}
+	}
+	if _, err := s.UploadBigFile(ctx, filepath.Join(folder, "a.txt"), "dev", "multipart"); err != nil {
+		t.Errorf("case 3 got error %v", err)
+	}
+
+	s = space.NewFromBackend(corruptBackend{space.NewMemoryBackend("dev.bucket")}).
+		WithEnvironments(envs).
+		WithVerify(true)
+	if _, err := s.UploadFile(ctx, filepath.Join(folder, "a.txt"), "dev", "test"); err == nil || !strings.Contains(err.Error(), "Verification failed") {
+		t.Errorf("case 4 got %v, want verification error", err)
+	}
+	if _, err := s.WithVerify(false).UploadFile(ctx, filepath.Join(folder, "a.txt"), "dev", "test"); err != nil {
+		t.Errorf("case 5 got error %v", err)
+	}
+}
+
+func TestDownloadFolder(t *testing.T) {
+	files := map[string]string{
+		"a.txt":         "a",
+		"foo/b.txt":     "b",
+		"foo/bar/c.txt": "c",
+	}
+	folder := setupFolder(t, files)
+	defer os.RemoveAll(folder)
+
+	s := space.NewFromBackend(space.NewMemoryBackend("dev.bucket")).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"}).
+		WithJobs(2)
+	ctx := context.Background()
+	if _, err := s.UploadFolder(ctx, folder, "dev", "test"); err != nil {
+		t.Fatal(err)
+	}
+	s.Put(ctx, "dev.bucket", "testing.txt", strings.NewReader("x"), 1, space.PutObjectOptions{})
+
+	output := filepath.Join(folder, "output")
+	filePaths, err := s.DownloadFolder(ctx, "test", output, "dev")
+	if err != nil || len(filePaths) != len(files) {
+		t.Fatalf("case 1 got %v, %v, want %v files", filePaths, err, len(files))
+	}
+	for name, content := range files {
+		data, err := ioutil.ReadFile(filepath.Join(output, filepath.FromSlash(name)))
+		if err != nil || string(data) != content {
+			t.Errorf("case 1 got %q, %v for %v, want %q", data, err, name, content)
+		}
+	}
+
+	filePaths, err = s.DownloadFolder(ctx, "test/foo/", filepath.Join(folder, "foo-output"), "dev")
+	if err != nil || len(filePaths) != 2 {
+		t.Errorf("case 2 got %v, %v, want 2 files", filePaths, err)
+	}
+
+	if _, err = s.DownloadFolder(ctx, "test", output, "prod"); err == nil {
+		t.Error("case 3 got no error, want invalid environment")
+	}
+}
+
+func TestPromote(t *testing.T) {
+	b := space.NewMemoryBackend("dev.bucket", "live.bucket")
+	s := space.NewFromBackend(b).
+		WithEnvironments(map[string]string{"dev": "dev.bucket", "live": "live.bucket", "same": "dev.bucket"}).
+		WithVerify(true)
+	ctx := context.Background()
+	s.Put(ctx, "dev.bucket", "app/a.txt", strings.NewReader("a"), 1, space.PutObjectOptions{
+		ContentType:  "text/plain",
+		UserMetadata: map[string]string{"Version": "1.0"},
+		UserTags:     map[string]string{"type": "app"},
+	})
+	s.Put(ctx, "dev.bucket", "app/b.log", strings.NewReader("b"), 1, space.PutObjectOptions{})
+	s.Put(ctx, "dev.bucket", "other.txt", strings.NewReader("x"), 1, space.PutObjectOptions{})
+	s.Put(ctx, "dev.bucket", "app.txt", strings.NewReader("x"), 1, space.PutObjectOptions{})
+	s.Put(ctx, "dev.bucket", "app2/c.txt", strings.NewReader("x"), 1, space.PutObjectOptions{})
+	uploadID, _ := b.NewMultipartUpload(ctx, "dev.bucket", "app/big.bin", space.PutObjectOptions{})
+	part, _ := b.PutObjectPart(ctx, "dev.bucket", "app/big.bin", uploadID, 1, strings.NewReader("big"), 3)
+	b.CompleteMultipartUpload(ctx, "dev.bucket", "app/big.bin", uploadID, []space.CompletePart{{PartNumber: 1, ETag: part.ETag}})
+
+	promoted, err := s.WithFilter(space.Glob("*.txt"), space.Glob("*.bin")).Promote(ctx, "app/", "dev", "live")
+	if err != nil || len(promoted) != 0 {
+		t.Errorf("case 1 got %v, %v, want nothing promoted since filters never match together", promoted, err)
+	}
+	promoted, err = s.WithFilter(space.Not(space.Glob("*.log"))).Promote(ctx, "app", "dev", "live")
+	if err != nil || len(promoted) != 2 {
+		t.Fatalf("case 2 got %v, %v, want 2 objects of app/ promoted", promoted, err)
+	}
+
+	info, err := s.Stat(ctx, "live.bucket", "app/a.txt", space.StatObjectOptions{})
+	if err != nil || info.ContentType != "text/plain" || info.UserMetadata["Version"] != "1.0" {
+		t.Errorf("case 3 got %v, %v, want content type and metadata of source", info, err)
+	}
+	if info.UserMetadata[space.PromotedFromMetadata] != "dev" || info.UserMetadata[space.PromotedByMetadata] == "" || info.UserMetadata[space.PromotedAtMetadata] == "" {
+		t.Errorf("case 3 got metadata %v, want promotion recorded", info.UserMetadata)
+	}
+	if tags, err := s.GetTags(ctx, "live.bucket", "app/a.txt"); err != nil || tags["type"] != "app" {
+		t.Errorf("case 3 got tags %v, %v, want type: app", tags, err)
+	}
+	if _, err = s.Stat(ctx, "live.bucket", "app/b.log", space.StatObjectOptions{}); !errors.Is(err, space.ErrNotFound) {
+		t.Errorf("case 3 got %v for filtered object, want not found", err)
+	}
+
+	promoted, err = s.PromoteObjects(ctx, "dev", "live", []string{"other.txt", "missing.txt"})
+	tErr := &space.TransferError{}
+	if !errors.As(err, &tErr) || len(tErr.Failed) != 1 || tErr.Failed[0].Path != "dev.bucket/missing.txt" || len(promoted) != 1 {
+		t.Errorf("case 4 got %v, %v, want missing.txt failed", promoted, err)
+	}
+
+	if _, err = s.Promote(ctx, "app/", "dev", "same"); err == nil {
+		t.Error("case 5 got no error, want same bucket error")
+	}
+	if _, err = s.Promote(ctx, "app/", "dev", "prod"); !errors.Is(err, space.ErrInvalidEnv) {
+		t.Errorf("case 6 got %v, want invalid environment", err)
+	}
+}
+
+func TestCopyAndMove(t *testing.T) {
+	s := space.NewFromBackend(space.NewMemoryBackend("dev.bucket", "archive.bucket")).
+		WithVerify(true)
+	ctx := context.Background()
+	for _, name := range []string{"assets/a.txt", "assets/img/b.png", "assets.txt"} {
+		s.Put(ctx, "dev.bucket", name, strings.NewReader(name), int64(len(name)), space.PutObjectOptions{
+			UserTags: map[string]string{"name": name},
+		})
+	}
+	exists := func(bucket, objectName string) bool {
+		_, err := s.Stat(ctx, bucket, objectName, space.StatObjectOptions{})
+		return err == nil
+	}
+
+	if err := s.Copy(ctx, "dev.bucket", "assets.txt", "archive.bucket", "copy.txt", nil); err != nil || !exists("dev.bucket", "assets.txt") {
+		t.Errorf("case 1 got %v, want source kept", err)
+	}
+	if tags, err := s.GetTags(ctx, "archive.bucket", "copy.txt"); err != nil || tags["name"] != "assets.txt" {
+		t.Errorf("case 1 got tags %v, %v, want tags of source", tags, err)
+	}
+
+	if err := s.Move(ctx, "dev.bucket", "assets.txt", "dev.bucket", "moved.txt"); err != nil || exists("dev.bucket", "assets.txt") || !exists("dev.bucket", "moved.txt") {
+		t.Errorf("case 2 got %v, want assets.txt renamed to moved.txt", err)
+	}
+	if err := s.Move(ctx, "dev.bucket", "moved.txt", "dev.bucket", "moved.txt"); err == nil {
+		t.Error("case 3 got no error, want same source and destination error")
+	}
+
+	objectNames, err := s.CopyPrefix(ctx, "dev.bucket", "assets", "archive.bucket", "v1/assets")
+	sort.Strings(objectNames)
+	if err != nil || fmt.Sprint(objectNames) != "[v1/assets/a.txt v1/assets/img/b.png]" {
+		t.Errorf("case 4 got %v, %v, want both assets copied", objectNames, err)
+	}
+
+	objectNames, err = s.WithFilter(space.Glob("*.png")).MovePrefix(ctx, "dev.bucket", "assets/", "dev.bucket", "images")
+	if err != nil || fmt.Sprint(objectNames) != "[images/img/b.png]" || exists("dev.bucket", "assets/img/b.png") || !exists("dev.bucket", "assets/a.txt") {
+		t.Errorf("case 5 got %v, %v, want only b.png moved", objectNames, err)
+	}
+
+	_, err = s.CopyPrefix(ctx, "dev.bucket", "images", "missing.bucket", "images")
+	tErr := &space.TransferError{}
+	if !errors.As(err, &tErr) || len(tErr.Failed) != 1 || !errors.Is(tErr.Failed[0].Err, space.ErrBucketNotFound) {
+		t.Errorf("case 6 got %v, want bucket not found", err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	folder := setupFolder(t, map[string]string{
+		"a.txt":     "a",
+		"foo/b.txt": "b",
+		"c.log":     "c",
+	})
+	defer os.RemoveAll(folder)
+
+	s := space.NewFromBackend(space.NewMemoryBackend("dev.bucket")).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"}).
+		WithIgnore(ignore.New("*.log"))
+	ctx := context.Background()
+	ops := func(actions []space.SyncAction) (ops []string) {
+		for _, action := range actions {
+			ops = append(ops, fmt.Sprintf("%v %v %v", action.Op, filepath.Base(action.Path), action.ObjectName))
+		}
+		return ops
+	}
+
+	actions, err := s.PlanPush(ctx, folder, "dev", "site")
+	if got := ops(actions); err != nil || len(got) != 2 || got[0] != "upload a.txt site/a.txt" || got[1] != "upload b.txt site/foo/b.txt" {
+		t.Fatalf("case 1 got %v, %v, want 2 uploads", got, err)
+	}
+	if _, err = s.Sync(ctx, "dev", actions); err != nil {
+		t.Fatalf("case 1 got error %v", err)
+	}
+	if actions, err = s.PlanPush(ctx, folder, "dev", "site"); err != nil || len(actions) != 0 {
+		t.Errorf("case 2 got %v, %v, want nothing to sync", ops(actions), err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(folder, "a.txt"), future, future)
+	ioutil.WriteFile(filepath.Join(folder, "foo/b.txt"), []byte("bb"), 0644)
+	s.Put(ctx, "dev.bucket", "site/extra.txt", strings.NewReader("x"), 1, space.PutObjectOptions{})
+	actions, err = s.WithDelete(true).PlanPush(ctx, folder, "dev", "site")
+	if got := ops(actions); err != nil || len(got) != 2 || got[0] != "upload b.txt site/foo/b.txt" || got[1] != "remove-object . site/extra.txt" {
+		t.Errorf("case 3 got %v, %v, want upload of b.txt and removal of extra.txt", got, err)
+	}
+	reported := []string{}
+	report := func(result space.TransferResult) {
+		reported = append(reported, fmt.Sprintf("%v %v", result.Op, result.ObjectName))
+	}
+	if _, err = s.WithReport(report).Sync(ctx, "dev", actions); err != nil {
+		t.Fatalf("case 3 got error %v", err)
+	}
+	if sort.Strings(reported); len(reported) != 2 || reported[0] != "remove-object site/extra.txt" || reported[1] != "upload site/foo/b.txt" {
+		t.Errorf("case 3 reported %v, want upload and removal", reported)
+	}
+
+	output := filepath.Join(folder, "output")
+	actions, err = s.PlanPull(ctx, "site", output, "dev")
+	if err != nil || len(actions) != 2 {
+		t.Fatalf("case 4 got %v, %v, want 2 downloads", ops(actions), err)
+	}
+	if _, err = s.Sync(ctx, "dev", actions); err != nil {
+		t.Fatalf("case 4 got error %v", err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(output, "foo", "b.txt")); string(data) != "bb" {
+		t.Errorf("case 4 got %q, want bb", data)
+	}
+
+	ioutil.WriteFile(filepath.Join(output, "stale.txt"), []byte("x"), 0644)
+	actions, err = s.WithDelete(true).PlanPull(ctx, "site", output, "dev")
+	if got := ops(actions); err != nil || len(got) != 1 || got[0] != "remove-file stale.txt " {
+		t.Errorf("case 5 got %v, %v, want removal of stale.txt", got, err)
+	}
+
+	if actions, err = s.WithDelete(true).PlanPush(ctx, folder, "dev", ""); err == nil {
+		t.Errorf("case 6 got %v, want error for delete without prefix", ops(actions))
+	}
+}
+
+func TestUsage(t *testing.T) {
+	s := space.NewFromBackend(space.NewMemoryBackend("dev.bucket")).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"})
+	ctx := context.Background()
+	objects := map[string]int{
+		"releases/readme.txt":       1,
+		"releases/v1/app.tar.gz":    10,
+		"releases/v1/docs/index.md": 2,
+		"releases/v2/app.tar.gz":    20,
+		"other/a.txt":               100,
+	}
+	for name, size := range objects {
+		s.Put(ctx, "dev.bucket", name, strings.NewReader(strings.Repeat("x", size)), int64(size), space.PutObjectOptions{})
+	}
+
+	cases := []struct {
+		depth int
+		want  []space.PrefixUsage
+	}{
+		{0, []space.PrefixUsage{{"releases/", 4, 33}}},
+		{1, []space.PrefixUsage{{"releases/", 4, 33}, {"releases/v1/", 2, 12}, {"releases/v2/", 1, 20}}},
+		{2, []space.PrefixUsage{{"releases/", 4, 33}, {"releases/v1/", 2, 12}, {"releases/v1/docs/", 1, 2}, {"releases/v2/", 1, 20}}},
+	}
+	for i, c := range cases {
+		usage, err := s.Usage(context.Background(), "dev", "releases", c.depth)
+		if err != nil || fmt.Sprint(usage) != fmt.Sprint(c.want) {
+			t.Errorf("case %v got %v, %v, want %v", i+1, usage, err, c.want)
+		}
+	}
+
+	usage, err := s.Usage(context.Background(), "dev", "", 0)
+	if err != nil || len(usage) != 1 || usage[0].Objects != 5 || usage[0].Size != 133 {
+		t.Errorf("case 4 got %v, %v, want 5 objects of 133 bytes", usage, err)
+	}
+
+	if usage, err = s.Usage(context.Background(), "dev", "releases", -1); err == nil {
+		t.Errorf("case 5 got %v, want error", usage)
+	}
+}
+
+func TestTree(t *testing.T) {
+	s := space.NewFromBackend(space.NewMemoryBackend("dev.bucket")).
+		WithEnvironments(map[string]string{"dev": "dev.bucket"})
+	ctx := context.Background()
+	for name, size := range map[string]int{
+		"assets/readme.txt":         1,
+		"assets/img/logo.png":       10,
+		"assets/img/icons/home.svg": 2,
+		"assets/css/site.css":       20,
+	} {
+		s.Put(ctx, "dev.bucket", name, strings.NewReader(strings.Repeat("x", size)), int64(size), space.PutObjectOptions{})
+	}
+
+	names := func(node *space.TreeNode) (names []string) {
+		for _, child := range node.Children {
+			names = append(names, fmt.Sprintf("%v:%v:%v", child.Name, child.Objects, child.Size))
+		}
+		return names
+	}
+
+	root, err := s.Tree(context.Background(), "dev", "assets", 0)
+	if err != nil {
+		t.Fatalf("case 1 got error %v", err)
+	}
+	if root.Objects != 4 || root.Size != 33 || fmt.Sprint(names(root)) != "[css/:1:20 img/:2:12 readme.txt:1:1]" {
+		t.Errorf("case 1 got %v objects %v bytes %v", root.Objects, root.Size, names(root))
+	}
+	if img := root.Children[1]; fmt.Sprint(names(img)) != "[icons/:1:2 logo.png:1:10]" || len(img.Children[0].Children) != 1 {
+		t.Errorf("case 1 got img/ %v", names(img))
+	}
+
+	root, _ = s.Tree(context.Background(), "dev", "assets/", 1)
+	if img := root.Children[1]; img.Objects != 2 || len(img.Children) != 0 {
+		t.Errorf("case 2 got img/ with %v objects %v, want totals only", img.Objects, names(img))
+	}
+
+	root, _ = s.WithFilter(space.Glob("*.css")).Tree(context.Background(), "dev", "assets", 0)
+	if root.Objects != 1 || fmt.Sprint(names(root)) != "[css/:1:20]" {
+		t.Errorf("case 3 got %v", names(root))
+	}
+}
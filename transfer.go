@@ -0,0 +1,99 @@
+package space
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultJobs is the number of concurrent transfers unless set with `WithJobs`.
+const DefaultJobs = 4
+
+// TransferResult of a single file uploaded or downloaded by folder tasks, or object copied by `CopyPrefix` and alike.
+type TransferResult struct {
+	Path       string
+	ObjectName string
+	// Op is the planned action of `Sync`, empty for other tasks.
+	Op  SyncOp
+	Err error
+}
+
+// TransferError lists every file that failed in a folder task.
+type TransferError struct {
+	Failed []TransferResult
+}
+
+func (e *TransferError) Error() string {
+	lines := []string{fmt.Sprintf("%v file(s) failed:", len(e.Failed))}
+	for _, result := range e.Failed {
+		lines = append(lines, fmt.Sprintf("%v: %v", result.Path, result.Err))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WithJobs sets the number of concurrent transfers in folder tasks.
+func (s Space) WithJobs(jobs int) Space {
+	s.jobs = jobs
+	return s
+}
+
+// WithReport calls `report` once for each file transferred by folder tasks, as soon as it's done.
+// Calls are never concurrent.
+func (s Space) WithReport(report func(TransferResult)) Space {
+	s.report = report
+	return s
+}
+
+// transfer runs `task` for every planned file with a bounded worker pool, continuing past failures.
+// Files not yet started when `ctx` is done fail with the context error.
+// Results are returned in the same order as the planned files.
+func (s Space) transfer(ctx context.Context, planned []TransferResult, task func(TransferResult) TransferResult) (results []TransferResult, err error) {
+	jobs := s.jobs
+	if jobs <= 0 {
+		jobs = DefaultJobs
+	}
+
+	indexCh := make(chan int)
+	doneCh := make(chan int)
+	results = make([]TransferResult, len(planned))
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				results[i] = planned[i]
+				if results[i].Err = ctx.Err(); results[i].Err == nil {
+					results[i] = task(planned[i])
+				}
+				doneCh <- i
+			}
+		}()
+	}
+
+	go func() {
+		for i := range planned {
+			indexCh <- i
+		}
+		close(indexCh)
+		wg.Wait()
+		close(doneCh)
+	}()
+
+	failed := []TransferResult{}
+	for i := range doneCh {
+		if s.report != nil {
+			s.report(results[i])
+		}
+		if results[i].Err != nil {
+			failed = append(failed, results[i])
+		}
+	}
+
+	if len(failed) > 0 {
+		err = &TransferError{Failed: failed}
+	}
+	return results, err
+}
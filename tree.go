@@ -0,0 +1,94 @@
+package space
+
+// Directory tree of a prefix, built with delimiter listing one level at a time.
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// TreeNode is an object or a directory (`Name` with trailing "/") in a key hierarchy.
+// Directories have totals of every object below them.
+type TreeNode struct {
+	Name         string
+	Key          string
+	Objects      int64
+	Size         int64
+	LastModified time.Time
+	Children     []*TreeNode
+}
+
+// IsDir tells whether the node is a prefix rather than an object.
+func (n *TreeNode) IsDir() bool {
+	return strings.HasSuffix(n.Key, "/") || n.Key == ""
+}
+
+// Tree of objects under `prefix` in an environment, expanding at most `maxDepth` levels, or every level if not positive.
+// Directories below `maxDepth` only have totals. Objects are selected with `WithFilter`, directories left empty are skipped.
+func (s Space) Tree(ctx context.Context, env, prefix string, maxDepth int) (root *TreeNode, err error) {
+	bucket, err := s.Bucket(env)
+	if err != nil {
+		return
+	}
+	prefix = folderPrefix(prefix)
+	root = &TreeNode{Name: prefix, Key: prefix}
+	err = s.expand(ctx, bucket, root, 1, maxDepth)
+	return root, err
+}
+
+// expand a directory node with its children, down to `maxDepth`.
+func (s Space) expand(ctx context.Context, bucket string, dir *TreeNode, depth, maxDepth int) error {
+	err := s.WalkObjects(ctx, bucket, ListOptions{Prefix: dir.Key}, func(object ObjectInfo) error {
+		name := strings.TrimPrefix(object.Key, dir.Key)
+		if name == "" {
+			return nil
+		}
+		node := &TreeNode{Name: name, Key: object.Key}
+		if !node.IsDir() {
+			node.add(object.Size, object.LastModified)
+		}
+		dir.Children = append(dir.Children, node)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	children := dir.Children[:0]
+	for _, child := range dir.Children {
+		if child.IsDir() {
+			if maxDepth > 0 && depth >= maxDepth {
+				err = s.WalkObjects(ctx, bucket, ListOptions{Prefix: child.Key, Recursive: true}, func(object ObjectInfo) error {
+					child.add(object.Size, object.LastModified)
+					return nil
+				})
+			} else {
+				err = s.expand(ctx, bucket, child, depth+1, maxDepth)
+			}
+			if err != nil {
+				return err
+			}
+			if child.Objects == 0 && s.filter != nil {
+				continue
+			}
+		}
+		dir.Objects += child.Objects
+		dir.Size += child.Size
+		if child.LastModified.After(dir.LastModified) {
+			dir.LastModified = child.LastModified
+		}
+		children = append(children, child)
+	}
+	dir.Children = children
+	return nil
+}
+
+// add an object to node's totals.
+func (n *TreeNode) add(size int64, lastModified time.Time) {
+	n.Objects++
+	n.Size += size
+	if lastModified.After(n.LastModified) {
+		n.LastModified = lastModified
+	}
+}
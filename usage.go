@@ -0,0 +1,62 @@
+package space
+
+// Disk usage of objects aggregated per prefix.
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PrefixUsage is the number of objects and their total size under a prefix, including sub-prefixes.
+type PrefixUsage struct {
+	Prefix  string
+	Objects int64
+	Size    int64
+}
+
+// Usage of objects under `prefix` in an environment, aggregated for every sub-prefix up to `depth` levels below it.
+// Like `du`, each level includes everything below it, so the first entry is the total of `prefix` itself.
+// Entries are sorted by prefix, objects are selected with `WithFilter`. A negative `depth` is invalid.
+func (s Space) Usage(ctx context.Context, env, prefix string, depth int) (usage []PrefixUsage, err error) {
+	if depth < 0 {
+		return nil, fmt.Errorf("Invalid depth %v, want 0 or more", depth)
+	}
+	prefix = folderPrefix(prefix)
+	totals := map[string]*PrefixUsage{prefix: {Prefix: prefix}}
+
+	err = s.Walk(ctx, env, ListOptions{Prefix: prefix, Recursive: true}, func(object ObjectInfo) error {
+		dirs := strings.Split(strings.TrimPrefix(object.Key, prefix), "/")
+		dirs = dirs[:len(dirs)-1]
+		if len(dirs) > depth {
+			dirs = dirs[:depth]
+		}
+
+		for level := 0; level <= len(dirs); level++ {
+			name := prefix
+			if level > 0 {
+				name += strings.Join(dirs[:level], "/") + "/"
+			}
+			total, ok := totals[name]
+			if !ok {
+				total = &PrefixUsage{Prefix: name}
+				totals[name] = total
+			}
+			total.Objects++
+			total.Size += object.Size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, total := range totals {
+		usage = append(usage, *total)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Prefix < usage[j].Prefix
+	})
+	return usage, nil
+}
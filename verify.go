@@ -0,0 +1,141 @@
+package space
+
+// Checksums of uploaded files, compared against Space after upload with `WithVerify`.
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ChecksumMetadata is the user metadata key holding SHA-256 of files uploaded with `WithVerify`.
+const ChecksumMetadata = "Sha256"
+
+// Checksum of a local file as hex digests.
+type Checksum struct {
+	Size   int64
+	MD5    string
+	SHA256 string
+}
+
+// FileChecksum computes MD5 and SHA-256 of a file in a single pass.
+func FileChecksum(fp string) (sum Checksum, err error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	sum.Size, err = io.Copy(io.MultiWriter(md5Hash, sha256Hash), f)
+	if err != nil {
+		return
+	}
+	sum.MD5 = hex.EncodeToString(md5Hash.Sum(nil))
+	sum.SHA256 = hex.EncodeToString(sha256Hash.Sum(nil))
+	return
+}
+
+// WithVerify checks every file uploaded with `Upload*` functions against Space once uploaded.
+// SHA-256 of the file is stored in its user metadata, see `ChecksumMetadata`.
+func (s Space) WithVerify(verify bool) Space {
+	s.verify = verify
+	return s
+}
+
+// uploadOptions for a file, with its checksum in user metadata when verifying.
+func (s Space) uploadOptions(fp string) (options PutObjectOptions, sum Checksum, err error) {
+	options.ContentType = "application/octet-stream"
+	if !s.verify {
+		return
+	}
+	if sum, err = FileChecksum(fp); err != nil {
+		return
+	}
+	options.UserMetadata = map[string]string{ChecksumMetadata: sum.SHA256}
+	return
+}
+
+// verifyUpload compares uploaded object with local checksum. Single part upload has MD5 as its ETag,
+// multipart upload is checked against SHA-256 stored in its user metadata.
+func (s Space) verifyUpload(ctx context.Context, bucket, objectName string, sum Checksum) error {
+	info, err := s.backend.StatObject(ctx, bucket, objectName, StatObjectOptions{})
+	if err != nil {
+		return err
+	}
+	if info.Size != sum.Size {
+		return fmt.Errorf("Verification failed for %v: size is %v, want %v", objectName, info.Size, sum.Size)
+	}
+
+	etag := strings.Trim(info.ETag, `"`)
+	if !strings.Contains(etag, "-") {
+		if etag != sum.MD5 {
+			return fmt.Errorf("Verification failed for %v: MD5 is %v, want %v", objectName, etag, sum.MD5)
+		}
+		return nil
+	}
+
+	stored := info.UserMetadata[ChecksumMetadata]
+	if stored == "" {
+		return fmt.Errorf("Verification failed for %v: no SHA-256 stored in metadata", objectName)
+	}
+	if stored != sum.SHA256 {
+		return fmt.Errorf("Verification failed for %v: SHA-256 is %v, want %v", objectName, stored, sum.SHA256)
+	}
+	return nil
+}
+
+// verifyCopy compares a copied object with its source. Copies whose ETags can't be compared,
+// e.g. of multipart uploads, are checked against SHA-256 in source's user metadata or computed from its content.
+func (s Space) verifyCopy(ctx context.Context, srcBucket string, src ObjectInfo, dstBucket, dstObject string) error {
+	info, err := s.backend.StatObject(ctx, dstBucket, dstObject, StatObjectOptions{})
+	if err != nil {
+		return err
+	}
+	if info.Size != src.Size {
+		return fmt.Errorf("Verification failed for %v: size is %v, want %v", dstObject, info.Size, src.Size)
+	}
+
+	etag, want := strings.Trim(info.ETag, `"`), strings.Trim(src.ETag, `"`)
+	if !strings.Contains(etag, "-") && !strings.Contains(want, "-") {
+		if etag != want {
+			return fmt.Errorf("Verification failed for %v: MD5 is %v, want %v", dstObject, etag, want)
+		}
+		return nil
+	}
+
+	want = src.UserMetadata[ChecksumMetadata]
+	if want == "" {
+		if want, err = s.objectSHA256(ctx, srcBucket, src.Key); err != nil {
+			return err
+		}
+	}
+	sum, err := s.objectSHA256(ctx, dstBucket, dstObject)
+	if err != nil {
+		return err
+	}
+	if sum != want {
+		return fmt.Errorf("Verification failed for %v: SHA-256 is %v, want %v", dstObject, sum, want)
+	}
+	return nil
+}
+
+// objectSHA256 as hex digest, reading the whole object.
+func (s Space) objectSHA256(ctx context.Context, bucket, objectName string) (string, error) {
+	object, err := s.backend.GetObject(ctx, bucket, objectName, GetObjectOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer object.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, object); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,82 @@
+package space
+
+// Paginated listing, so huge buckets can be listed without buffering every key.
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// DefaultPageSize of `ListOptions`, the maximum allowed by S3.
+const DefaultPageSize = 1000
+
+// ErrStopWalk can be returned by `WalkObjects` callback to stop listing without error.
+var ErrStopWalk = errors.New("stop walk")
+
+// ListOptions for paginated listing.
+type ListOptions struct {
+	Prefix    string
+	Recursive bool
+	// StartAfter lists keys after this one, e.g. the last key seen by an earlier listing.
+	StartAfter string
+	// PageSize is the number of objects fetched per request, `DefaultPageSize` if not set.
+	PageSize int
+}
+
+func (o ListOptions) pageSize() int {
+	if o.PageSize <= 0 || o.PageSize > DefaultPageSize {
+		return DefaultPageSize
+	}
+	return o.PageSize
+}
+
+// ListObjectsPage returns a single page of objects and the token to get the next one, empty on the last page.
+func (s Space) ListObjectsPage(ctx context.Context, bucketName string, options ListOptions, token string) ([]ObjectInfo, string, error) {
+	return s.backend.ListObjectsPage(ctx, bucketName, options, token)
+}
+
+// WalkObjects calls `fn` for every object in a bucket selected by `WithFilter` as pages arrive,
+// stopping at the first error.
+func (s Space) WalkObjects(ctx context.Context, bucketName string, options ListOptions, fn func(ObjectInfo) error) error {
+	token := ""
+	for {
+		objects, next, err := s.backend.ListObjectsPage(ctx, bucketName, options, token)
+		if err != nil {
+			return err
+		}
+		for _, object := range objects {
+			// Prefixes collapsed by non-recursive listing aren't objects, so they're not filtered.
+			if options.Recursive || !strings.HasSuffix(object.Key, "/") {
+				ok, err := s.selected(ctx, bucketName, object)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+			}
+			if err = fn(object); err == ErrStopWalk {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		token = next
+	}
+}
+
+// Walk objects in an environment, see `WalkObjects`.
+func (s Space) Walk(ctx context.Context, env string, options ListOptions, fn func(ObjectInfo) error) error {
+	bucket, err := s.Bucket(env)
+	if err != nil {
+		return err
+	}
+	return s.WalkObjects(ctx, bucket, options, fn)
+}